@@ -2,7 +2,9 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -27,6 +29,20 @@ type Config struct {
 	RoutingAPI        RoutingAPIConfig `yaml:"routing_api"`
 	HaProxyPidFile    string           `yaml:"haproxy_pid_file"`
 	IsolationSegments []string         `yaml:"isolation_segments"`
+	PortOptionsFile   string           `yaml:"port_options_file"`
+	SourceACLFile     string           `yaml:"source_acl_file"`
+	PortDefaults      PortOptions      `yaml:"port_defaults"`
+	TLS               TLSConfig        `yaml:"tls"`
+	Resolvers         ResolversConfig  `yaml:"resolvers"`
+	HaProxy           HaProxyConfig    `yaml:"haproxy"`
+	// DrainTimeout keeps deleted backends without new connections for up to
+	// the timeout, or until their sessions end.
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+	// EmptyPortHoldDown keeps a port bound for the hold down after it loses
+	// its last backend, so that clients do not see it close and reopen.
+	EmptyPortHoldDown time.Duration `yaml:"empty_port_hold_down"`
+	// RateLimits overrides the rate limit of individual ports.
+	RateLimits map[uint16]RateLimitOptions `yaml:"rate_limits"`
 }
 
 func New(path string) (*Config, error) {
@@ -51,5 +67,73 @@ func (c *Config) initConfigFromFile(path string) error {
 	if c.HaProxyPidFile == "" {
 		return errors.New("haproxy_pid_file is required")
 	}
+
+	e = c.PortDefaults.Validate()
+	if e != nil {
+		return fmt.Errorf("port_defaults: %s", e.Error())
+	}
+
+	e = c.TLS.Validate()
+	if e != nil {
+		return fmt.Errorf("tls: %s", e.Error())
+	}
+
+	if c.DrainTimeout < 0 {
+		return errors.New("drain_timeout must not be negative")
+	}
+
+	if c.EmptyPortHoldDown < 0 {
+		return errors.New("empty_port_hold_down must not be negative")
+	}
+
+	e = c.Resolvers.Validate()
+	if e != nil {
+		return fmt.Errorf("resolvers: %s", e.Error())
+	}
+
+	e = c.HaProxy.Validate()
+	if e != nil {
+		return fmt.Errorf("haproxy: %s", e.Error())
+	}
+
+	for port, rateLimit := range c.RateLimits {
+		if port == 0 {
+			return errors.New("rate_limits: invalid port 0")
+		}
+		e = rateLimit.Validate()
+		if e != nil {
+			return fmt.Errorf("rate_limits: port %d: %s", port, e.Error())
+		}
+	}
 	return nil
 }
+
+// PortOptions combines the port options file with the port defaults, rate
+// limits and TLS certificates of the router config. Options from the file take
+// precedence over the defaults, and per-port rate limits over the file.
+func (c *Config) PortOptions(portOptions PortOptionsConfig) PortOptionsConfig {
+	portOptions = portOptions.LayeredOver(c.PortDefaults)
+	if len(c.TLS.Certificates) == 0 && len(c.RateLimits) == 0 {
+		return portOptions
+	}
+
+	ports := make(map[uint16]PortOptions, len(portOptions.Ports)+len(c.TLS.Certificates)+len(c.RateLimits))
+	for port, options := range portOptions.Ports {
+		ports[port] = options
+	}
+	for port := range c.TLS.Certificates {
+		options := ports[port]
+		options.TLSCertificate = c.TLS.CertificatePath(port)
+		if _, ok := c.TLS.ClientAuth[port]; ok {
+			options.ClientCAFile, options.ClientCRLFile = c.TLS.ClientAuthPaths(port)
+		}
+		ports[port] = options
+	}
+	for port, rateLimit := range c.RateLimits {
+		options := ports[port]
+		options.RateLimit = options.RateLimit.Merge(rateLimit)
+		ports[port] = options
+	}
+	portOptions.Ports = ports
+	return portOptions
+}
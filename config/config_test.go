@@ -1,6 +1,8 @@
 package config_test
 
 import (
+	"time"
+
 	"code.cloudfoundry.org/cf-tcp-router/config"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -10,6 +12,7 @@ var _ = Describe("Config", func() {
 
 	Context("when a valid config", func() {
 		It("loads the config", func() {
+			healthChecksEnabled := true
 			expectedCfg := config.Config{
 				OAuth: config.OAuthConfig{
 					TokenEndpoint:     "uaa.service.cf.internal",
@@ -26,6 +29,31 @@ var _ = Describe("Config", func() {
 				},
 				HaProxyPidFile:    "/path/to/pid/file",
 				IsolationSegments: []string{"foo-iso-seg"},
+				PortOptionsFile:   "/path/to/port_options.yml",
+				SourceACLFile:     "/path/to/source_acls.yml",
+				PortDefaults: config.PortOptions{
+					HealthCheck: config.HealthCheckOptions{Enabled: &healthChecksEnabled, Interval: 5 * time.Second},
+					SendProxy:   config.SendProxyV2,
+				},
+				TLS: config.TLSConfig{
+					CertDirectory: "/path/to/certs",
+					Certificates:  map[uint16]string{443: "tcp_router.pem"},
+					ClientAuth:    map[uint16]config.ClientAuthConfig{443: {CAFile: "client_ca.pem"}},
+				},
+				HaProxy: config.HaProxyConfig{
+					Enabled: true,
+					Global:  config.HaProxyGlobalConfig{MaxConn: 4096, NbThread: 2, Logs: []string{"127.0.0.1:514 local0 info"}},
+					Defaults: config.HaProxyDefaultsConfig{
+						ConnectTimeout: 5 * time.Second,
+						ClientTimeout:  5 * time.Minute,
+					},
+					ListenTemplate: "/path/to/listen.tmpl",
+				},
+				DrainTimeout:      30 * time.Second,
+				EmptyPortHoldDown: 2 * time.Minute,
+				RateLimits: map[uint16]config.RateLimitOptions{
+					5432: {ConnectionRate: 50, Period: time.Second},
+				},
 			}
 			cfg, err := config.New("fixtures/valid_config.yml")
 			Expect(err).NotTo(HaveOccurred())
@@ -48,6 +76,101 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Context("when port defaults are invalid", func() {
+		It("return error", func() {
+			_, err := config.New("fixtures/invalid_port_defaults.yml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("port_defaults"))
+		})
+	})
+
+	Context("when port defaults have an invalid PROXY protocol setting", func() {
+		It("return error", func() {
+			_, err := config.New("fixtures/invalid_proxy_protocol.yml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("send_proxy"))
+		})
+	})
+
+	Context("when a relative certificate is given without a cert directory", func() {
+		It("return error", func() {
+			_, err := config.New("fixtures/invalid_tls.yml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cert_directory"))
+		})
+	})
+
+	Context("when a nameserver is invalid", func() {
+		It("return error", func() {
+			_, err := config.New("fixtures/invalid_resolvers.yml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("resolvers"))
+		})
+	})
+
+	Context("when the drain timeout is negative", func() {
+		It("return error", func() {
+			_, err := config.New("fixtures/invalid_drain_timeout.yml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("drain_timeout"))
+		})
+	})
+
+	Context("when the empty port hold down is negative", func() {
+		It("return error", func() {
+			_, err := config.New("fixtures/invalid_empty_port_hold_down.yml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("empty_port_hold_down"))
+		})
+	})
+
+	Context("when the haproxy stats socket is relative", func() {
+		It("return error", func() {
+			_, err := config.New("fixtures/invalid_haproxy.yml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("haproxy: global: stats_socket"))
+		})
+	})
+
+	Context("when a rate limit is invalid", func() {
+		It("return error", func() {
+			_, err := config.New("fixtures/invalid_rate_limits.yml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("rate_limits: port 5432"))
+		})
+	})
+
+	Describe("PortOptions", func() {
+		It("combines the port options with the port defaults and certificates", func() {
+			cfg, err := config.New("fixtures/valid_config.yml")
+			Expect(err).NotTo(HaveOccurred())
+
+			portOptions := cfg.PortOptions(config.PortOptionsConfig{
+				Ports: map[uint16]config.PortOptions{443: {MaxConn: 10}},
+			})
+			Expect(portOptions.For(443).MaxConn).To(Equal(10))
+			Expect(portOptions.For(443).TLSCertificate).To(Equal("/path/to/certs/tcp_router.pem"))
+			Expect(portOptions.For(443).SendProxy).To(Equal(config.SendProxyV2))
+			Expect(portOptions.For(443).ClientCAFile).To(Equal("/path/to/certs/client_ca.pem"))
+			Expect(portOptions.For(443).ClientCRLFile).To(BeEmpty())
+			Expect(portOptions.For(2000).TLSCertificate).To(BeEmpty())
+		})
+
+		It("layers the rate limits of the router config over the port options", func() {
+			cfg, err := config.New("fixtures/valid_config.yml")
+			Expect(err).NotTo(HaveOccurred())
+
+			portOptions := cfg.PortOptions(config.PortOptionsConfig{
+				Defaults: config.PortOptions{RateLimit: config.RateLimitOptions{MaxConnections: 10}},
+				Ports: map[uint16]config.PortOptions{
+					5432: {RateLimit: config.RateLimitOptions{ConnectionRate: 500, Period: time.Minute}},
+				},
+			})
+			Expect(portOptions.For(5432).RateLimit).To(Equal(config.RateLimitOptions{ConnectionRate: 50, Period: time.Second, MaxConnections: 10}))
+			Expect(portOptions.For(2000).RateLimit).To(Equal(config.RateLimitOptions{MaxConnections: 10}))
+		})
+	})
+
 	Context("when haproxy pid file is missing", func() {
 		It("return error", func() {
 			_, err := config.New("fixtures/no_haproxy.yml")
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HaProxyConfig generates the global and defaults sections of the load
+// balancer configuration. While it is disabled, the base configuration file
+// provides them; while enabled, the base configuration file is optional and
+// is added after the generated sections.
+//
+// ListenTemplate is the path of a Go text/template that renders the listen
+// section of each port in place of the built-in template.
+type HaProxyConfig struct {
+	Enabled        bool                  `yaml:"enabled"`
+	Global         HaProxyGlobalConfig   `yaml:"global"`
+	Defaults       HaProxyDefaultsConfig `yaml:"defaults"`
+	ListenTemplate string                `yaml:"listen_template"`
+}
+
+// StatsSocket defaults to the stats socket the router collects metrics from.
+// Logs are HAProxy log targets, e.g. "127.0.0.1:514 local0 info".
+type HaProxyGlobalConfig struct {
+	MaxConn     int      `yaml:"maxconn"`
+	NbThread    int      `yaml:"nbthread"`
+	StatsSocket string   `yaml:"stats_socket"`
+	Logs        []string `yaml:"logs"`
+}
+
+type HaProxyDefaultsConfig struct {
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	ClientTimeout  time.Duration `yaml:"client_timeout"`
+	ServerTimeout  time.Duration `yaml:"server_timeout"`
+	MaxConn        int           `yaml:"maxconn"`
+}
+
+func (h HaProxyConfig) Validate() error {
+	if h.Global.MaxConn < 0 {
+		return errors.New("global: maxconn must not be negative")
+	}
+	if h.Global.NbThread < 0 {
+		return errors.New("global: nbthread must not be negative")
+	}
+	if h.Global.StatsSocket != "" && !filepath.IsAbs(h.Global.StatsSocket) {
+		return fmt.Errorf("global: stats_socket %q must be an absolute path", h.Global.StatsSocket)
+	}
+	for _, log := range h.Global.Logs {
+		if strings.TrimSpace(log) == "" || strings.Contains(log, "\n") {
+			return fmt.Errorf("global: invalid log target %q", log)
+		}
+	}
+	if h.Defaults.ConnectTimeout < 0 || h.Defaults.ClientTimeout < 0 || h.Defaults.ServerTimeout < 0 {
+		return errors.New("defaults: timeouts must not be negative")
+	}
+	if h.Defaults.MaxConn < 0 {
+		return errors.New("defaults: maxconn must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Peer is a router instance that shares stick table state. HAProxy treats the
+// peer whose name matches its hostname as the local instance.
+type Peer struct {
+	Name    string `yaml:"name"`
+	Address string `yaml:"address"`
+}
+
+func ValidatePeers(peers []Peer) error {
+	names := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		if peer.Name == "" || strings.ContainsAny(peer.Name, " \t") {
+			return fmt.Errorf("invalid peer name %q", peer.Name)
+		}
+		if names[peer.Name] {
+			return fmt.Errorf("duplicate peer name %q", peer.Name)
+		}
+		names[peer.Name] = true
+		if _, _, err := net.SplitHostPort(peer.Address); err != nil {
+			return fmt.Errorf("peer %s: %s", peer.Name, err.Error())
+		}
+	}
+	return nil
+}
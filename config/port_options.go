@@ -0,0 +1,502 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	BalanceRoundRobin = "roundrobin"
+	BalanceLeastConn  = "leastconn"
+	BalanceSource     = "source"
+)
+
+const (
+	SendProxyOff = "off"
+	SendProxyV1  = "v1"
+	SendProxyV2  = "v2"
+)
+
+const MaxBackendWeight = 256
+
+// PortOptions tunes the listen configuration of a port. Zero values are unset
+// and leave the load balancer defaults in place.
+type PortOptions struct {
+	Balance       string        `yaml:"balance"`
+	ClientTimeout time.Duration `yaml:"client_timeout"`
+	ServerTimeout time.Duration `yaml:"server_timeout"`
+	MaxConn       int           `yaml:"maxconn"`
+
+	// QueueTimeout bounds the time a connection waits for a server that is at
+	// ServerMaxConn. Retries and Redispatch retry failed connects, the last
+	// retry going to another server when redispatching.
+	QueueTimeout time.Duration `yaml:"queue_timeout"`
+	Retries      int           `yaml:"retries"`
+	Redispatch   *bool         `yaml:"redispatch"`
+
+	// SlowStart ramps up the weight of a server over the duration after it
+	// comes up. ServerMaxConn and ServerMaxQueue limit the connections a server
+	// takes at once and the connections queued for it.
+	SlowStart      time.Duration `yaml:"slowstart"`
+	ServerMaxConn  int           `yaml:"server_maxconn"`
+	ServerMaxQueue int           `yaml:"server_maxqueue"`
+
+	// BackendWeights overrides the weight of backends, keyed by "address:port"
+	// with IPv6 addresses in brackets.
+	BackendWeights map[string]int `yaml:"backend_weights"`
+
+	HealthCheck HealthCheckOptions `yaml:"health_check"`
+
+	// SendProxy selects the PROXY protocol version sent to backends.
+	SendProxy string `yaml:"send_proxy"`
+	// AcceptProxy requires connections to the port to start with a PROXY
+	// protocol header, as sent by upstream load balancers.
+	AcceptProxy *bool `yaml:"accept_proxy"`
+	// DualStack binds the port on both IPv6 and IPv4 instead of IPv4 only.
+	DualStack *bool `yaml:"dual_stack"`
+	// FallbackBackend, as "address:port", takes the connections of a port
+	// that is held open after losing its last backend. Without it those
+	// connections are rejected.
+	FallbackBackend string `yaml:"fallback_backend"`
+	// BackupBackends, as "address:port", take the connections of the port
+	// only while none of its route backends are up.
+	BackupBackends []string `yaml:"backup_backends"`
+	// Metadata is passed as is to the listen template of the port.
+	Metadata map[string]string `yaml:"metadata"`
+
+	// TLSCertificate is the PEM file used to terminate TLS on the port. It is
+	// set from the tls section of the router config.
+	TLSCertificate string `yaml:"-"`
+	// ClientCAFile and ClientCRLFile require clients of a port that terminates
+	// TLS to present a certificate. They are set from the router config.
+	ClientCAFile  string `yaml:"-"`
+	ClientCRLFile string `yaml:"-"`
+
+	BackendTLS BackendTLSOptions `yaml:"backend_tls"`
+
+	RateLimit RateLimitOptions `yaml:"rate_limit"`
+
+	// Peers share the stick tables of the port. They are set from the peers
+	// of the port options file.
+	Peers []Peer `yaml:"-"`
+
+	// SourceACL is set from the source ACL file.
+	SourceACL SourceACL `yaml:"-"`
+
+	// Nameservers resolve backends given as hostnames, with HoldValid as the
+	// time a resolution is trusted. They are set from the router config.
+	Nameservers []string      `yaml:"-"`
+	HoldValid   time.Duration `yaml:"-"`
+}
+
+// BackendTLSOptions re-encrypts traffic to the backends of a port. Backend
+// certificates are always verified against CAFile. ClientCertificate is a PEM
+// file with a certificate and key presented to backends that require mTLS.
+type BackendTLSOptions struct {
+	Enabled           *bool  `yaml:"enabled"`
+	CAFile            string `yaml:"ca_file"`
+	SNI               string `yaml:"sni"`
+	ClientCertificate string `yaml:"client_certificate"`
+}
+
+// RateLimitOptions limits connections per source IP. ConnectionRate is the
+// number of new connections allowed per Period, MaxConnections the number of
+// concurrent connections. Connections over either limit are rejected.
+type RateLimitOptions struct {
+	ConnectionRate int           `yaml:"connection_rate"`
+	Period         time.Duration `yaml:"period"`
+	MaxConnections int           `yaml:"max_connections"`
+}
+
+// HealthCheckOptions configures active TCP health checks of the backends of a
+// port. Checks are disabled unless Enabled is set.
+type HealthCheckOptions struct {
+	Enabled       *bool         `yaml:"enabled"`
+	Interval      time.Duration `yaml:"interval"`
+	Rise          int           `yaml:"rise"`
+	Fall          int           `yaml:"fall"`
+	ObserveLayer4 *bool         `yaml:"observe_layer4"`
+}
+
+// PortOptionsConfig is the operator maintained port options file. Options
+// for a port are layered over the defaults. Peers lists every router instance
+// that shares stick tables.
+type PortOptionsConfig struct {
+	Defaults PortOptions            `yaml:"defaults"`
+	Ports    map[uint16]PortOptions `yaml:"ports"`
+	Peers    []Peer                 `yaml:"peers"`
+}
+
+func LoadPortOptions(path string) (PortOptionsConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return PortOptionsConfig{}, err
+	}
+
+	var c PortOptionsConfig
+	err = yaml.Unmarshal(b, &c)
+	if err != nil {
+		return PortOptionsConfig{}, err
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return PortOptionsConfig{}, err
+	}
+	return c, nil
+}
+
+// For returns the options that apply to port.
+func (c PortOptionsConfig) For(port uint16) PortOptions {
+	options := c.Defaults.Merge(c.Ports[port])
+	options.Peers = c.Peers
+	return options
+}
+
+// LayeredOver returns the config with its defaults layered over defaults.
+func (c PortOptionsConfig) LayeredOver(defaults PortOptions) PortOptionsConfig {
+	c.Defaults = defaults.Merge(c.Defaults)
+	return c
+}
+
+func (c PortOptionsConfig) Validate() error {
+	err := c.Defaults.Validate()
+	if err != nil {
+		return fmt.Errorf("defaults: %s", err.Error())
+	}
+	for port, options := range c.Ports {
+		if port == 0 {
+			return fmt.Errorf("ports: invalid port 0")
+		}
+		err = options.Validate()
+		if err != nil {
+			return fmt.Errorf("port %d: %s", port, err.Error())
+		}
+	}
+	err = ValidatePeers(c.Peers)
+	if err != nil {
+		return fmt.Errorf("peers: %s", err.Error())
+	}
+	return nil
+}
+
+// Merge returns o with every option that is set in override replaced.
+func (o PortOptions) Merge(override PortOptions) PortOptions {
+	if override.Balance != "" {
+		o.Balance = override.Balance
+	}
+	if override.ClientTimeout != 0 {
+		o.ClientTimeout = override.ClientTimeout
+	}
+	if override.ServerTimeout != 0 {
+		o.ServerTimeout = override.ServerTimeout
+	}
+	if override.MaxConn != 0 {
+		o.MaxConn = override.MaxConn
+	}
+	if override.QueueTimeout != 0 {
+		o.QueueTimeout = override.QueueTimeout
+	}
+	if override.Retries != 0 {
+		o.Retries = override.Retries
+	}
+	if override.Redispatch != nil {
+		o.Redispatch = override.Redispatch
+	}
+	if override.SlowStart != 0 {
+		o.SlowStart = override.SlowStart
+	}
+	if override.ServerMaxConn != 0 {
+		o.ServerMaxConn = override.ServerMaxConn
+	}
+	if override.ServerMaxQueue != 0 {
+		o.ServerMaxQueue = override.ServerMaxQueue
+	}
+	if override.BackendWeights != nil {
+		o.BackendWeights = override.BackendWeights
+	}
+	o.HealthCheck = o.HealthCheck.Merge(override.HealthCheck)
+	if override.SendProxy != "" {
+		o.SendProxy = override.SendProxy
+	}
+	if override.AcceptProxy != nil {
+		o.AcceptProxy = override.AcceptProxy
+	}
+	if override.DualStack != nil {
+		o.DualStack = override.DualStack
+	}
+	if override.FallbackBackend != "" {
+		o.FallbackBackend = override.FallbackBackend
+	}
+	if override.BackupBackends != nil {
+		o.BackupBackends = override.BackupBackends
+	}
+	if override.Metadata != nil {
+		o.Metadata = override.Metadata
+	}
+	if override.TLSCertificate != "" {
+		o.TLSCertificate = override.TLSCertificate
+	}
+	if override.ClientCAFile != "" {
+		o.ClientCAFile = override.ClientCAFile
+	}
+	if override.ClientCRLFile != "" {
+		o.ClientCRLFile = override.ClientCRLFile
+	}
+	o.BackendTLS = o.BackendTLS.Merge(override.BackendTLS)
+	o.RateLimit = o.RateLimit.Merge(override.RateLimit)
+	if !override.SourceACL.IsEmpty() {
+		o.SourceACL = override.SourceACL
+	}
+	if override.Nameservers != nil {
+		o.Nameservers = override.Nameservers
+	}
+	if override.HoldValid != 0 {
+		o.HoldValid = override.HoldValid
+	}
+	return o
+}
+
+// Files returns the files the options refer to.
+func (o PortOptions) Files() []string {
+	files := []string{}
+	for _, file := range []string{o.TLSCertificate, o.ClientCAFile, o.ClientCRLFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	if o.BackendTLS.IsEnabled() {
+		files = append(files, o.BackendTLS.CAFile)
+		if o.BackendTLS.ClientCertificate != "" {
+			files = append(files, o.BackendTLS.ClientCertificate)
+		}
+	}
+	return files
+}
+
+// ValidateFiles checks that every file the options of a port need is given
+// and exists. Unlike Validate it applies to the options after layering.
+func (c PortOptionsConfig) ValidateFiles() error {
+	err := c.Defaults.validateFiles()
+	if err != nil {
+		return fmt.Errorf("defaults: %s", err.Error())
+	}
+	for port := range c.Ports {
+		err = c.For(port).validateFiles()
+		if err != nil {
+			return fmt.Errorf("port %d: %s", port, err.Error())
+		}
+	}
+	return nil
+}
+
+func (o PortOptions) validateFiles() error {
+	if o.BackendTLS.IsEnabled() && o.BackendTLS.CAFile == "" {
+		return fmt.Errorf("backend_tls: ca_file is required")
+	}
+	for _, file := range o.Files() {
+		if _, err := os.Stat(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r RateLimitOptions) Merge(override RateLimitOptions) RateLimitOptions {
+	if override.ConnectionRate != 0 {
+		r.ConnectionRate = override.ConnectionRate
+	}
+	if override.Period != 0 {
+		r.Period = override.Period
+	}
+	if override.MaxConnections != 0 {
+		r.MaxConnections = override.MaxConnections
+	}
+	return r
+}
+
+func (r RateLimitOptions) IsEnabled() bool {
+	return r.ConnectionRate > 0 || r.MaxConnections > 0
+}
+
+func (r RateLimitOptions) Validate() error {
+	if r.ConnectionRate < 0 {
+		return fmt.Errorf("rate_limit: connection_rate must not be negative")
+	}
+	if r.Period < 0 {
+		return fmt.Errorf("rate_limit: period must not be negative")
+	}
+	if r.Period%time.Millisecond != 0 {
+		return fmt.Errorf("rate_limit: period must be a whole number of milliseconds")
+	}
+	if r.MaxConnections < 0 {
+		return fmt.Errorf("rate_limit: max_connections must not be negative")
+	}
+	return nil
+}
+
+func (b BackendTLSOptions) Merge(override BackendTLSOptions) BackendTLSOptions {
+	if override.Enabled != nil {
+		b.Enabled = override.Enabled
+	}
+	if override.CAFile != "" {
+		b.CAFile = override.CAFile
+	}
+	if override.SNI != "" {
+		b.SNI = override.SNI
+	}
+	if override.ClientCertificate != "" {
+		b.ClientCertificate = override.ClientCertificate
+	}
+	return b
+}
+
+func (b BackendTLSOptions) IsEnabled() bool {
+	return b.Enabled != nil && *b.Enabled
+}
+
+func (b BackendTLSOptions) Validate() error {
+	if strings.ContainsAny(b.SNI, " \t()") {
+		return fmt.Errorf("backend_tls: invalid sni %q", b.SNI)
+	}
+	return nil
+}
+
+func (o PortOptions) AcceptsProxy() bool {
+	return o.AcceptProxy != nil && *o.AcceptProxy
+}
+
+func (o PortOptions) IsDualStack() bool {
+	return o.DualStack != nil && *o.DualStack
+}
+
+func (o PortOptions) Redispatches() bool {
+	return o.Redispatch != nil && *o.Redispatch
+}
+
+func (h HealthCheckOptions) Merge(override HealthCheckOptions) HealthCheckOptions {
+	if override.Enabled != nil {
+		h.Enabled = override.Enabled
+	}
+	if override.Interval != 0 {
+		h.Interval = override.Interval
+	}
+	if override.Rise != 0 {
+		h.Rise = override.Rise
+	}
+	if override.Fall != 0 {
+		h.Fall = override.Fall
+	}
+	if override.ObserveLayer4 != nil {
+		h.ObserveLayer4 = override.ObserveLayer4
+	}
+	return h
+}
+
+func (h HealthCheckOptions) IsEnabled() bool {
+	return h.Enabled != nil && *h.Enabled
+}
+
+func (h HealthCheckOptions) ObservesLayer4() bool {
+	return h.ObserveLayer4 != nil && *h.ObserveLayer4
+}
+
+func (h HealthCheckOptions) Validate() error {
+	if h.Interval < 0 {
+		return fmt.Errorf("health_check: interval must not be negative")
+	}
+	if h.Rise < 0 {
+		return fmt.Errorf("health_check: rise must not be negative")
+	}
+	if h.Fall < 0 {
+		return fmt.Errorf("health_check: fall must not be negative")
+	}
+	return nil
+}
+
+func (o PortOptions) Validate() error {
+	switch o.Balance {
+	case "", BalanceRoundRobin, BalanceLeastConn, BalanceSource:
+	default:
+		return fmt.Errorf("unsupported balance algorithm %q", o.Balance)
+	}
+	switch o.SendProxy {
+	case "", SendProxyOff, SendProxyV1, SendProxyV2:
+	default:
+		return fmt.Errorf("send_proxy must be one of %s, %s or %s", SendProxyOff, SendProxyV1, SendProxyV2)
+	}
+	if o.ClientTimeout < 0 {
+		return fmt.Errorf("client_timeout must not be negative")
+	}
+	if o.ServerTimeout < 0 {
+		return fmt.Errorf("server_timeout must not be negative")
+	}
+	if o.MaxConn < 0 {
+		return fmt.Errorf("maxconn must not be negative")
+	}
+	if o.QueueTimeout < 0 {
+		return fmt.Errorf("queue_timeout must not be negative")
+	}
+	if o.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	if o.SlowStart < 0 {
+		return fmt.Errorf("slowstart must not be negative")
+	}
+	if o.ServerMaxConn < 0 {
+		return fmt.Errorf("server_maxconn must not be negative")
+	}
+	if o.ServerMaxQueue < 0 {
+		return fmt.Errorf("server_maxqueue must not be negative")
+	}
+	for backend, weight := range o.BackendWeights {
+		if _, _, err := net.SplitHostPort(backend); err != nil {
+			return fmt.Errorf("backend_weights: %s", err.Error())
+		}
+		if weight < 1 || weight > MaxBackendWeight {
+			return fmt.Errorf("backend_weights: weight of %s must be between 1 and %d", backend, MaxBackendWeight)
+		}
+	}
+	if o.FallbackBackend != "" {
+		err := validateBackendAddress(o.FallbackBackend)
+		if err != nil {
+			return fmt.Errorf("fallback_backend: %s", err.Error())
+		}
+	}
+	for _, backend := range o.BackupBackends {
+		err := validateBackendAddress(backend)
+		if err != nil {
+			return fmt.Errorf("backup_backends: %s", err.Error())
+		}
+	}
+	err := o.HealthCheck.Validate()
+	if err != nil {
+		return err
+	}
+	err = o.RateLimit.Validate()
+	if err != nil {
+		return err
+	}
+	return o.BackendTLS.Validate()
+}
+
+func validateBackendAddress(backend string) error {
+	host, port, err := net.SplitHostPort(backend)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return fmt.Errorf("missing host in address %q", backend)
+	}
+	if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package config_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/cf-tcp-router/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PortOptions", func() {
+	Context("when a valid port options file", func() {
+		var (
+			portOptions config.PortOptionsConfig
+			enabled     = true
+			disabled    = false
+		)
+
+		BeforeEach(func() {
+			var err error
+			portOptions, err = config.LoadPortOptions("fixtures/port_options.yml")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("loads the port options", func() {
+			Expect(portOptions).To(Equal(config.PortOptionsConfig{
+				Defaults: config.PortOptions{
+					Balance:       config.BalanceRoundRobin,
+					ClientTimeout: 30 * time.Second,
+					ServerTimeout: 30 * time.Second,
+					QueueTimeout:  5 * time.Second,
+					SlowStart:     30 * time.Second,
+					HealthCheck: config.HealthCheckOptions{
+						Enabled:  &enabled,
+						Interval: 2 * time.Second,
+						Fall:     3,
+					},
+				},
+				Ports: map[uint16]config.PortOptions{
+					1024: {
+						Balance:        config.BalanceLeastConn,
+						MaxConn:        100,
+						SendProxy:      config.SendProxyOff,
+						AcceptProxy:    &disabled,
+						BackendWeights: map[string]int{"10.0.0.1:61000": 10, "10.0.0.2:61000": 90},
+						BackupBackends: []string{"maintenance.internal:8080"},
+					},
+					1025: {
+						Balance:         config.BalanceSource,
+						ServerTimeout:   time.Minute,
+						HealthCheck:     config.HealthCheckOptions{Enabled: &disabled},
+						FallbackBackend: "10.0.0.9:8080",
+						Metadata:        map[string]string{"team": "payments"},
+					},
+					1026: {
+						DualStack:      &enabled,
+						BackendWeights: map[string]int{"[fd00::1]:61000": 20},
+					},
+				},
+				Peers: []config.Peer{
+					{Name: "tcp-router-0", Address: "10.0.16.10:10000"},
+					{Name: "tcp-router-1", Address: "10.0.16.11:10000"},
+				},
+			}))
+			Expect(portOptions.For(1026).IsDualStack()).To(BeTrue())
+			Expect(portOptions.For(1024).IsDualStack()).To(BeFalse())
+		})
+
+		It("layers the options of a port over the defaults", func() {
+			Expect(portOptions.For(1025)).To(Equal(config.PortOptions{
+				Balance:       config.BalanceSource,
+				ClientTimeout: 30 * time.Second,
+				ServerTimeout: time.Minute,
+				QueueTimeout:  5 * time.Second,
+				SlowStart:     30 * time.Second,
+				HealthCheck: config.HealthCheckOptions{
+					Enabled:  &disabled,
+					Interval: 2 * time.Second,
+					Fall:     3,
+				},
+				FallbackBackend: "10.0.0.9:8080",
+				Metadata:        map[string]string{"team": "payments"},
+				Peers:           portOptions.Peers,
+			}))
+			Expect(portOptions.For(1025).HealthCheck.IsEnabled()).To(BeFalse())
+		})
+
+		It("layers the defaults over the given defaults", func() {
+			layered := portOptions.LayeredOver(config.PortOptions{
+				MaxConn:     500,
+				HealthCheck: config.HealthCheckOptions{Rise: 2, Fall: 5},
+			})
+			Expect(layered.For(2000).MaxConn).To(Equal(500))
+			Expect(layered.For(2000).HealthCheck.Rise).To(Equal(2))
+			Expect(layered.For(2000).HealthCheck.Fall).To(Equal(3))
+			Expect(layered.For(1024).MaxConn).To(Equal(100))
+		})
+
+		It("lets a port turn off PROXY protocol enabled by the defaults", func() {
+			acceptProxy := true
+			layered := portOptions.LayeredOver(config.PortOptions{SendProxy: config.SendProxyV2, AcceptProxy: &acceptProxy})
+			Expect(layered.For(2000).SendProxy).To(Equal(config.SendProxyV2))
+			Expect(layered.For(2000).AcceptsProxy()).To(BeTrue())
+			Expect(layered.For(1024).SendProxy).To(Equal(config.SendProxyOff))
+			Expect(layered.For(1024).AcceptsProxy()).To(BeFalse())
+		})
+
+		It("returns the defaults and peers for ports without options", func() {
+			expected := portOptions.Defaults
+			expected.Peers = portOptions.Peers
+			Expect(portOptions.For(2000)).To(Equal(expected))
+		})
+	})
+
+	Describe("ValidateFiles", func() {
+		var (
+			portOptions config.PortOptionsConfig
+			enabled     = true
+		)
+
+		BeforeEach(func() {
+			portOptions = config.PortOptionsConfig{
+				Defaults: config.PortOptions{BackendTLS: config.BackendTLSOptions{CAFile: "fixtures/certs/cert_only.pem"}},
+				Ports: map[uint16]config.PortOptions{
+					1024: {BackendTLS: config.BackendTLSOptions{Enabled: &enabled}},
+				},
+			}
+		})
+
+		It("accepts options whose files exist", func() {
+			Expect(portOptions.ValidateFiles()).To(Succeed())
+		})
+
+		It("rejects a missing client certificate", func() {
+			portOptions.Ports[1024] = config.PortOptions{BackendTLS: config.BackendTLSOptions{Enabled: &enabled, ClientCertificate: "fixtures/certs/missing.pem"}}
+			err := portOptions.ValidateFiles()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("port 1024"))
+		})
+
+		It("rejects backend TLS without a CA file", func() {
+			portOptions.Defaults = config.PortOptions{}
+			err := portOptions.ValidateFiles()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("ca_file"))
+		})
+	})
+
+	Context("when given an invalid port options file", func() {
+		Context("non existing file", func() {
+			It("return error", func() {
+				_, err := config.LoadPortOptions("fixtures/non_existing_port_options.yml")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("unsupported PROXY protocol version", func() {
+			It("return error", func() {
+				_, err := config.LoadPortOptions("fixtures/invalid_send_proxy_port_options.yml")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("send_proxy"))
+			})
+		})
+
+		Context("backend weight out of range", func() {
+			It("return error", func() {
+				_, err := config.LoadPortOptions("fixtures/invalid_backend_weight_port_options.yml")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("backend_weights"))
+			})
+		})
+
+		Context("fallback backend without a port", func() {
+			It("return error", func() {
+				_, err := config.LoadPortOptions("fixtures/invalid_fallback_port_options.yml")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fallback_backend"))
+			})
+		})
+
+		Context("fallback backend without a host", func() {
+			It("return error", func() {
+				_, err := config.LoadPortOptions("fixtures/invalid_fallback_host_port_options.yml")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fallback_backend"))
+			})
+		})
+
+		Context("backup backend with an invalid port", func() {
+			It("return error", func() {
+				_, err := config.LoadPortOptions("fixtures/invalid_backup_port_options.yml")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("backup_backends"))
+			})
+		})
+
+		Context("backup backend without a host", func() {
+			It("return error", func() {
+				_, err := config.LoadPortOptions("fixtures/invalid_backup_host_port_options.yml")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("backup_backends"))
+			})
+		})
+
+		Context("negative server queue limit", func() {
+			It("return error", func() {
+				_, err := config.LoadPortOptions("fixtures/invalid_queue_port_options.yml")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("server_maxqueue"))
+			})
+		})
+
+		Context("duplicate peer names", func() {
+			It("return error", func() {
+				_, err := config.LoadPortOptions("fixtures/invalid_peers_port_options.yml")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("peers: duplicate peer name"))
+			})
+		})
+
+		Context("rate period below a millisecond", func() {
+			It("return error", func() {
+				_, err := config.LoadPortOptions("fixtures/invalid_rate_limit_port_options.yml")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("rate_limit"))
+			})
+		})
+
+		Context("unsupported balance algorithm", func() {
+			It("return error", func() {
+				_, err := config.LoadPortOptions("fixtures/invalid_port_options.yml")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("port 1024"))
+			})
+		})
+	})
+})
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultResolvConf = "/etc/resolv.conf"
+	dnsPort           = "53"
+)
+
+// ResolversConfig lets the load balancer resolve backends that are given as
+// hostnames at runtime. Nameservers default to those of ResolvConf. Without
+// resolvers, hostnames are resolved once when the load balancer starts, and
+// backends whose name does not resolve start without an address.
+type ResolversConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Nameservers []string      `yaml:"nameservers"`
+	ResolvConf  string        `yaml:"resolv_conf"`
+	HoldValid   time.Duration `yaml:"hold_valid"`
+}
+
+func (r ResolversConfig) Validate() error {
+	for _, nameserver := range r.Nameservers {
+		if _, err := nameserverAddress(nameserver); err != nil {
+			return fmt.Errorf("nameservers: %s", err.Error())
+		}
+	}
+	if r.HoldValid < 0 {
+		return fmt.Errorf("hold_valid must not be negative")
+	}
+	return nil
+}
+
+// ResolvConfPath returns the resolv.conf nameservers are read from, or "" if
+// they are configured explicitly.
+func (r ResolversConfig) ResolvConfPath() string {
+	if !r.Enabled || len(r.Nameservers) > 0 {
+		return ""
+	}
+	if r.ResolvConf == "" {
+		return DefaultResolvConf
+	}
+	return r.ResolvConf
+}
+
+// LoadNameservers returns the "address:port" of every nameserver, or none if
+// resolvers are disabled.
+func (r ResolversConfig) LoadNameservers() ([]string, error) {
+	if !r.Enabled {
+		return nil, nil
+	}
+
+	nameservers := r.Nameservers
+	if len(nameservers) == 0 {
+		var err error
+		nameservers, err = readResolvConf(r.ResolvConfPath())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	addresses := make([]string, 0, len(nameservers))
+	for _, nameserver := range nameservers {
+		address, err := nameserverAddress(nameserver)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+func readResolvConf(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	nameservers := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			nameservers = append(nameservers, fields[1])
+		}
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	if len(nameservers) == 0 {
+		return nil, fmt.Errorf("%s: no nameservers found", path)
+	}
+	return nameservers, nil
+}
+
+// nameserverAddress accepts an IP with or without a port.
+func nameserverAddress(nameserver string) (string, error) {
+	if ip := net.ParseIP(nameserver); ip != nil {
+		return net.JoinHostPort(ip.String(), dnsPort), nil
+	}
+	host, _, err := net.SplitHostPort(nameserver)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("nameserver %s is not an IP address", nameserver)
+	}
+	return nameserver, nil
+}
@@ -0,0 +1,57 @@
+package config_test
+
+import (
+	"code.cloudfoundry.org/cf-tcp-router/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResolversConfig", func() {
+	var resolvers config.ResolversConfig
+
+	BeforeEach(func() {
+		resolvers = config.ResolversConfig{Enabled: true, ResolvConf: "fixtures/resolv.conf"}
+	})
+
+	Describe("LoadNameservers", func() {
+		It("reads the nameservers of resolv.conf", func() {
+			nameservers, err := resolvers.LoadNameservers()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nameservers).To(Equal([]string{"169.254.0.2:53", "[fd00::53]:53"}))
+		})
+
+		It("prefers configured nameservers", func() {
+			resolvers.Nameservers = []string{"10.0.0.2", "10.0.0.3:5353"}
+			nameservers, err := resolvers.LoadNameservers()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nameservers).To(Equal([]string{"10.0.0.2:53", "10.0.0.3:5353"}))
+			Expect(resolvers.ResolvConfPath()).To(BeEmpty())
+		})
+
+		It("returns an error when resolv.conf has no nameservers", func() {
+			resolvers.ResolvConf = "fixtures/port_options.yml"
+			_, err := resolvers.LoadNameservers()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns no nameservers when disabled", func() {
+			resolvers.Enabled = false
+			nameservers, err := resolvers.LoadNameservers()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nameservers).To(BeEmpty())
+			Expect(resolvers.ResolvConfPath()).To(BeEmpty())
+		})
+
+		It("defaults to the resolv.conf of the host", func() {
+			resolvers.ResolvConf = ""
+			Expect(resolvers.ResolvConfPath()).To(Equal(config.DefaultResolvConf))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("rejects nameservers that are not IP addresses", func() {
+			resolvers.Nameservers = []string{"dns.internal"}
+			Expect(resolvers.Validate()).NotTo(Succeed())
+		})
+	})
+})
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SourceACL restricts the client addresses that may connect to a port. A
+// connection is rejected if its source matches Deny, or if Allow is given and
+// its source does not match Allow. Entries are CIDRs or single IPs.
+type SourceACL struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// SourceACLConfig is the operator maintained source ACL file.
+type SourceACLConfig struct {
+	Ports map[uint16]SourceACL `yaml:"ports"`
+}
+
+func LoadSourceACLs(path string) (SourceACLConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return SourceACLConfig{}, err
+	}
+
+	var c SourceACLConfig
+	err = yaml.Unmarshal(b, &c)
+	if err != nil {
+		return SourceACLConfig{}, err
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return SourceACLConfig{}, err
+	}
+	return c, nil
+}
+
+func (c SourceACLConfig) Validate() error {
+	for port, acl := range c.Ports {
+		if port == 0 {
+			return fmt.Errorf("ports: invalid port 0")
+		}
+		err := acl.Validate()
+		if err != nil {
+			return fmt.Errorf("port %d: %s", port, err.Error())
+		}
+	}
+	return nil
+}
+
+func (a SourceACL) IsEmpty() bool {
+	return len(a.Allow) == 0 && len(a.Deny) == 0
+}
+
+func (a SourceACL) Validate() error {
+	for _, source := range a.Allow {
+		if !isSource(source) {
+			return fmt.Errorf("allow: invalid source %q", source)
+		}
+	}
+	for _, source := range a.Deny {
+		if !isSource(source) {
+			return fmt.Errorf("deny: invalid source %q", source)
+		}
+	}
+	return nil
+}
+
+func isSource(source string) bool {
+	if net.ParseIP(source) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(source)
+	return err == nil
+}
+
+// WithSourceACLs returns the config with the source ACL of every port set.
+func (c PortOptionsConfig) WithSourceACLs(acls SourceACLConfig) PortOptionsConfig {
+	if len(acls.Ports) == 0 {
+		return c
+	}
+
+	ports := make(map[uint16]PortOptions, len(c.Ports)+len(acls.Ports))
+	for port, options := range c.Ports {
+		ports[port] = options
+	}
+	for port, acl := range acls.Ports {
+		options := ports[port]
+		options.SourceACL = acl
+		ports[port] = options
+	}
+	c.Ports = ports
+	return c
+}
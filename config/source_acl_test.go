@@ -0,0 +1,50 @@
+package config_test
+
+import (
+	"code.cloudfoundry.org/cf-tcp-router/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SourceACLConfig", func() {
+	Describe("LoadSourceACLs", func() {
+		It("loads the source ACLs of every port", func() {
+			acls, err := config.LoadSourceACLs("fixtures/source_acls.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acls.Ports).To(Equal(map[uint16]config.SourceACL{
+				5432: {Allow: []string{"10.0.0.0/8", "192.168.1.10"}, Deny: []string{"10.1.0.0/16"}},
+				6379: {Deny: []string{"fd00::/8"}},
+			}))
+		})
+
+		It("returns an error for an invalid source", func() {
+			_, err := config.LoadSourceACLs("fixtures/invalid_source_acls.yml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("port 5432"))
+		})
+
+		It("returns an error when the file does not exist", func() {
+			_, err := config.LoadSourceACLs("fixtures/missing.yml")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("WithSourceACLs", func() {
+		It("sets the source ACL of the ports without touching other options", func() {
+			portOptions := config.PortOptionsConfig{
+				Defaults: config.PortOptions{MaxConn: 100},
+				Ports:    map[uint16]config.PortOptions{5432: {Balance: config.BalanceLeastConn}},
+			}
+			acl := config.SourceACL{Allow: []string{"10.0.0.0/8"}}
+			withACLs := portOptions.WithSourceACLs(config.SourceACLConfig{
+				Ports: map[uint16]config.SourceACL{5432: acl, 6379: acl},
+			})
+			Expect(withACLs.For(5432).SourceACL).To(Equal(acl))
+			Expect(withACLs.For(5432).Balance).To(Equal(config.BalanceLeastConn))
+			Expect(withACLs.For(6379).SourceACL).To(Equal(acl))
+			Expect(withACLs.For(6379).MaxConn).To(Equal(100))
+			Expect(withACLs.For(2000).SourceACL.IsEmpty()).To(BeTrue())
+			Expect(portOptions.For(5432).SourceACL.IsEmpty()).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TLSConfig maps ports on which the router terminates TLS to the PEM file
+// holding the certificate chain and private key for the port. Ports listed in
+// ClientAuth also require clients to present a certificate. Relative paths
+// are resolved against CertDirectory.
+type TLSConfig struct {
+	CertDirectory string                      `yaml:"cert_directory"`
+	Certificates  map[uint16]string           `yaml:"certificates"`
+	ClientAuth    map[uint16]ClientAuthConfig `yaml:"client_auth"`
+}
+
+type ClientAuthConfig struct {
+	CAFile  string `yaml:"ca_file"`
+	CRLFile string `yaml:"crl_file"`
+}
+
+type Certificate struct {
+	Path     string
+	NotAfter time.Time
+}
+
+func (t TLSConfig) CertificatePath(port uint16) string {
+	return t.path(t.Certificates[port])
+}
+
+// ClientAuthPaths returns the resolved CA and CRL files of port.
+func (t TLSConfig) ClientAuthPaths(port uint16) (string, string) {
+	clientAuth := t.ClientAuth[port]
+	return t.path(clientAuth.CAFile), t.path(clientAuth.CRLFile)
+}
+
+func (t TLSConfig) path(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(t.CertDirectory, path)
+}
+
+func (t TLSConfig) Validate() error {
+	for port, path := range t.Certificates {
+		if port == 0 {
+			return errors.New("certificates: invalid port 0")
+		}
+		if path == "" {
+			return fmt.Errorf("certificates: port %d has no certificate", port)
+		}
+		if !filepath.IsAbs(path) && t.CertDirectory == "" {
+			return fmt.Errorf("certificates: cert_directory is required for relative certificate %s", path)
+		}
+	}
+	for port, clientAuth := range t.ClientAuth {
+		if _, ok := t.Certificates[port]; !ok {
+			return fmt.Errorf("client_auth: port %d does not terminate TLS", port)
+		}
+		if clientAuth.CAFile == "" {
+			return fmt.Errorf("client_auth: port %d has no ca_file", port)
+		}
+		for _, path := range []string{clientAuth.CAFile, clientAuth.CRLFile} {
+			if path != "" && !filepath.IsAbs(path) && t.CertDirectory == "" {
+				return fmt.Errorf("client_auth: cert_directory is required for relative file %s", path)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadCertificates reads and validates the certificate, client CA and CRL of
+// every port, so that a bad PEM file is refused before it reaches the load
+// balancer, and copies the validated files to directory. It returns the TLS
+// config of the copies, which a bad file rotated in later does not change,
+// and the certificates of the ports.
+func (t TLSConfig) LoadCertificates(directory string) (TLSConfig, map[uint16]Certificate, error) {
+	certificates := make(map[uint16]Certificate, len(t.Certificates))
+	copies := TLSConfig{
+		CertDirectory: directory,
+		Certificates:  make(map[uint16]string, len(t.Certificates)),
+		ClientAuth:    make(map[uint16]ClientAuthConfig, len(t.ClientAuth)),
+	}
+	files := map[string][]byte{}
+
+	for port := range t.Certificates {
+		path := t.CertificatePath(port)
+		pemBytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return TLSConfig{}, nil, fmt.Errorf("certificate for port %d: %s", port, err.Error())
+		}
+		certificate, err := parseCertificate(path, pemBytes)
+		if err != nil {
+			return TLSConfig{}, nil, fmt.Errorf("certificate for port %d: %s", port, err.Error())
+		}
+		certificates[port] = certificate
+		copies.Certificates[port] = fmt.Sprintf("%d.pem", port)
+		files[copies.Certificates[port]] = pemBytes
+	}
+
+	for port := range t.ClientAuth {
+		caFile, crlFile := t.ClientAuthPaths(port)
+		pemBytes, err := readCAFile(caFile)
+		if err != nil {
+			return TLSConfig{}, nil, fmt.Errorf("client ca for port %d: %s", port, err.Error())
+		}
+		clientAuth := ClientAuthConfig{CAFile: fmt.Sprintf("%d_ca.pem", port)}
+		files[clientAuth.CAFile] = pemBytes
+
+		if crlFile != "" {
+			pemBytes, err = readCRLFile(crlFile)
+			if err != nil {
+				return TLSConfig{}, nil, fmt.Errorf("crl for port %d: %s", port, err.Error())
+			}
+			clientAuth.CRLFile = fmt.Sprintf("%d_crl.pem", port)
+			files[clientAuth.CRLFile] = pemBytes
+		}
+		copies.ClientAuth[port] = clientAuth
+	}
+
+	if len(files) == 0 {
+		return copies, certificates, nil
+	}
+	err := os.MkdirAll(directory, 0700)
+	if err != nil {
+		return TLSConfig{}, nil, err
+	}
+	for name, content := range files {
+		err = writeFile(filepath.Join(directory, name), content)
+		if err != nil {
+			return TLSConfig{}, nil, err
+		}
+	}
+	return copies, certificates, nil
+}
+
+func readCAFile(path string) ([]byte, error) {
+	pemBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(pemBytes) {
+		return nil, fmt.Errorf("%s: no certificates found", path)
+	}
+	return pemBytes, nil
+}
+
+func readCRLFile(path string) ([]byte, error) {
+	pemBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(pemBytes)
+	if block == nil || block.Type != "X509 CRL" {
+		return nil, fmt.Errorf("%s: no CRL found", path)
+	}
+	_, err = x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return pemBytes, nil
+}
+
+func parseCertificate(path string, pemBytes []byte) (Certificate, error) {
+	keyPair, err := tls.X509KeyPair(pemBytes, pemBytes)
+	if err != nil {
+		return Certificate{}, fmt.Errorf("%s: %s", path, err.Error())
+	}
+
+	leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return Certificate{}, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return Certificate{Path: path, NotAfter: leaf.NotAfter}, nil
+}
+
+// Writes to a temporary file that is renamed into place, so that a reload of
+// the load balancer never reads a partly written file.
+func writeFile(path string, content []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,172 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/cf-tcp-router/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TLSConfig", func() {
+	var tlsConfig config.TLSConfig
+
+	BeforeEach(func() {
+		tlsConfig = config.TLSConfig{CertDirectory: "fixtures/certs"}
+	})
+
+	Describe("CertificatePath", func() {
+		It("resolves relative paths against the cert directory", func() {
+			tlsConfig.Certificates = map[uint16]string{443: "tcp_router.pem", 8443: "/etc/certs/other.pem"}
+			Expect(tlsConfig.CertificatePath(443)).To(Equal(filepath.Join("fixtures/certs", "tcp_router.pem")))
+			Expect(tlsConfig.CertificatePath(8443)).To(Equal("/etc/certs/other.pem"))
+		})
+	})
+
+	Describe("LoadCertificates", func() {
+		var directory string
+
+		BeforeEach(func() {
+			var err error
+			directory, err = ioutil.TempDir("", "certs")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(directory)).To(Succeed())
+		})
+
+		Context("when the certificates are valid", func() {
+			It("returns their expiry", func() {
+				tlsConfig.Certificates = map[uint16]string{443: "tcp_router.pem"}
+				_, certificates, err := tlsConfig.LoadCertificates(directory)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(certificates).To(HaveKey(uint16(443)))
+				Expect(certificates[443].Path).To(Equal(filepath.Join("fixtures/certs", "tcp_router.pem")))
+				Expect(certificates[443].NotAfter.Year()).To(BeNumerically(">", time.Now().Year()))
+			})
+
+			It("returns the TLS config of copies in the directory", func() {
+				tlsConfig.Certificates = map[uint16]string{443: "tcp_router.pem"}
+				tlsConfig.ClientAuth = map[uint16]config.ClientAuthConfig{443: {CAFile: "client_ca.pem", CRLFile: "client_ca.crl"}}
+				copies, _, err := tlsConfig.LoadCertificates(directory)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(copies.CertificatePath(443)).To(Equal(filepath.Join(directory, "443.pem")))
+				caFile, crlFile := copies.ClientAuthPaths(443)
+				Expect(caFile).To(Equal(filepath.Join(directory, "443_ca.pem")))
+				Expect(crlFile).To(Equal(filepath.Join(directory, "443_crl.pem")))
+				for copy, original := range map[string]string{
+					copies.CertificatePath(443): "fixtures/certs/tcp_router.pem",
+					caFile:                      "fixtures/certs/client_ca.pem",
+					crlFile:                     "fixtures/certs/client_ca.crl",
+				} {
+					Expect(ioutil.ReadFile(copy)).To(Equal(readFile(original)))
+				}
+			})
+		})
+
+		Context("when a bad certificate is rotated in", func() {
+			It("keeps the copies of the last valid certificates", func() {
+				certDirectory, err := ioutil.TempDir("", "rotated")
+				Expect(err).NotTo(HaveOccurred())
+				defer os.RemoveAll(certDirectory)
+
+				tlsConfig = config.TLSConfig{CertDirectory: certDirectory, Certificates: map[uint16]string{443: "tcp_router.pem"}}
+				Expect(ioutil.WriteFile(filepath.Join(certDirectory, "tcp_router.pem"), readFile("fixtures/certs/tcp_router.pem"), 0600)).To(Succeed())
+				copies, _, err := tlsConfig.LoadCertificates(directory)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(ioutil.WriteFile(filepath.Join(certDirectory, "tcp_router.pem"), readFile("fixtures/certs/corrupt.pem"), 0600)).To(Succeed())
+				_, _, err = tlsConfig.LoadCertificates(directory)
+				Expect(err).To(HaveOccurred())
+				Expect(ioutil.ReadFile(copies.CertificatePath(443))).To(Equal(readFile("fixtures/certs/tcp_router.pem")))
+			})
+		})
+
+		Context("when a certificate is not valid PEM", func() {
+			It("returns an error", func() {
+				tlsConfig.Certificates = map[uint16]string{443: "corrupt.pem"}
+				_, _, err := tlsConfig.LoadCertificates(directory)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("port 443"))
+			})
+		})
+
+		Context("when a certificate has no private key", func() {
+			It("returns an error", func() {
+				tlsConfig.Certificates = map[uint16]string{443: "cert_only.pem"}
+				_, _, err := tlsConfig.LoadCertificates(directory)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a certificate does not exist", func() {
+			It("returns an error", func() {
+				tlsConfig.Certificates = map[uint16]string{443: "missing.pem"}
+				_, _, err := tlsConfig.LoadCertificates(directory)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a port requires client certificates", func() {
+			BeforeEach(func() {
+				tlsConfig.Certificates = map[uint16]string{443: "tcp_router.pem"}
+			})
+
+			It("loads the client CA and CRL", func() {
+				tlsConfig.ClientAuth = map[uint16]config.ClientAuthConfig{443: {CAFile: "client_ca.pem", CRLFile: "client_ca.crl"}}
+				_, _, err := tlsConfig.LoadCertificates(directory)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns an error when the client CA holds no certificate", func() {
+				tlsConfig.ClientAuth = map[uint16]config.ClientAuthConfig{443: {CAFile: "corrupt.pem"}}
+				_, _, err := tlsConfig.LoadCertificates(directory)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("client ca for port 443"))
+			})
+
+			It("returns an error when the CRL is not a CRL", func() {
+				tlsConfig.ClientAuth = map[uint16]config.ClientAuthConfig{443: {CAFile: "client_ca.pem", CRLFile: "client_ca.pem"}}
+				_, _, err := tlsConfig.LoadCertificates(directory)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("crl for port 443"))
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		BeforeEach(func() {
+			tlsConfig.Certificates = map[uint16]string{443: "tcp_router.pem"}
+		})
+
+		It("accepts client auth on a port that terminates TLS", func() {
+			tlsConfig.ClientAuth = map[uint16]config.ClientAuthConfig{443: {CAFile: "client_ca.pem"}}
+			Expect(tlsConfig.Validate()).To(Succeed())
+		})
+
+		It("rejects client auth on a port without a certificate", func() {
+			tlsConfig.ClientAuth = map[uint16]config.ClientAuthConfig{8443: {CAFile: "client_ca.pem"}}
+			err := tlsConfig.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("does not terminate TLS"))
+		})
+
+		It("rejects client auth without a CA", func() {
+			tlsConfig.ClientAuth = map[uint16]config.ClientAuthConfig{443: {CRLFile: "client_ca.crl"}}
+			err := tlsConfig.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("ca_file"))
+		})
+	})
+})
+
+func readFile(path string) []byte {
+	content, err := ioutil.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred())
+	return content
+}
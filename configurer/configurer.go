@@ -3,6 +3,7 @@ package configurer
 import (
 	"errors"
 
+	"code.cloudfoundry.org/cf-tcp-router/config"
 	"code.cloudfoundry.org/cf-tcp-router/configurer/haproxy"
 	"code.cloudfoundry.org/cf-tcp-router/models"
 	"code.cloudfoundry.org/cf-tcp-router/monitor"
@@ -15,13 +16,14 @@ const (
 
 //go:generate counterfeiter -o fakes/fake_configurer.go . RouterConfigurer
 type RouterConfigurer interface {
-	Configure(routingTable models.RoutingTable) error
+	Configure(routingTable models.RoutingTableSnapshot) error
+	UpdatePortOptions(portOptions config.PortOptionsConfig) error
 }
 
-func NewConfigurer(logger lager.Logger, tcpLoadBalancer string, tcpLoadBalancerBaseCfg string, tcpLoadBalancerCfg string, monitor monitor.Monitor, scriptRunner haproxy.ScriptRunner) RouterConfigurer {
+func NewConfigurer(logger lager.Logger, tcpLoadBalancer string, baseSections config.HaProxyConfig, tcpLoadBalancerBaseCfg string, tcpLoadBalancerCfg string, monitor monitor.Monitor, scriptRunner haproxy.ScriptRunner) RouterConfigurer {
 	switch tcpLoadBalancer {
 	case HaProxyConfigurer:
-		routerHostInfo, err := haproxy.NewHaProxyConfigurer(logger, tcpLoadBalancerBaseCfg, tcpLoadBalancerCfg, monitor, scriptRunner)
+		routerHostInfo, err := haproxy.NewHaProxyConfigurer(logger, baseSections, tcpLoadBalancerBaseCfg, tcpLoadBalancerCfg, monitor, scriptRunner)
 		if err != nil {
 			logger.Fatal("could not create tcp load balancer",
 				err,
@@ -3,6 +3,7 @@ package configurer_test
 import (
 	"reflect"
 
+	"code.cloudfoundry.org/cf-tcp-router/config"
 	"code.cloudfoundry.org/cf-tcp-router/configurer"
 	"code.cloudfoundry.org/cf-tcp-router/configurer/haproxy"
 
@@ -19,7 +20,7 @@ var _ = Describe("Configurer", func() {
 		Context("when 'haproxy' tcp load balancer is passed", func() {
 			It("should return haproxy configurer", func() {
 				routeConfigurer := configurer.NewConfigurer(logger,
-					configurer.HaProxyConfigurer, "haproxy/fixtures/haproxy.cfg.template", "haproxy/fixtures/haproxy.cfg", nil, nil)
+					configurer.HaProxyConfigurer, config.HaProxyConfig{}, "haproxy/fixtures/haproxy.cfg.template", "haproxy/fixtures/haproxy.cfg", nil, nil)
 				Expect(routeConfigurer).ShouldNot(BeNil())
 				expectedType := reflect.PtrTo(reflect.TypeOf(haproxy.Configurer{}))
 				value := reflect.ValueOf(routeConfigurer)
@@ -29,7 +30,7 @@ var _ = Describe("Configurer", func() {
 			Context("when invalid config file is passed", func() {
 				It("should panic", func() {
 					Expect(func() {
-						configurer.NewConfigurer(logger, configurer.HaProxyConfigurer, "haproxy/fixtures/haproxy.cfg.template", "", nil, nil)
+						configurer.NewConfigurer(logger, configurer.HaProxyConfigurer, config.HaProxyConfig{}, "haproxy/fixtures/haproxy.cfg.template", "", nil, nil)
 					}).Should(Panic())
 				})
 			})
@@ -37,7 +38,7 @@ var _ = Describe("Configurer", func() {
 			Context("when invalid base config file is passed", func() {
 				It("should panic", func() {
 					Expect(func() {
-						configurer.NewConfigurer(logger, configurer.HaProxyConfigurer, "", "haproxy/fixtures/haproxy.cfg", nil, nil)
+						configurer.NewConfigurer(logger, configurer.HaProxyConfigurer, config.HaProxyConfig{}, "", "haproxy/fixtures/haproxy.cfg", nil, nil)
 					}).Should(Panic())
 				})
 			})
@@ -46,7 +47,7 @@ var _ = Describe("Configurer", func() {
 		Context("when non-supported tcp load balancer is passed", func() {
 			It("should panic", func() {
 				Expect(func() {
-					configurer.NewConfigurer(logger, "not-supported", "some-base-config-file", "some-config-file", nil, nil)
+					configurer.NewConfigurer(logger, "not-supported", config.HaProxyConfig{}, "some-base-config-file", "some-config-file", nil, nil)
 				}).Should(Panic())
 			})
 		})
@@ -54,7 +55,7 @@ var _ = Describe("Configurer", func() {
 		Context("when empty tcp load balancer is passed", func() {
 			It("should panic", func() {
 				Expect(func() {
-					configurer.NewConfigurer(logger, "", "some-base-config-file", "some-config-file", nil, nil)
+					configurer.NewConfigurer(logger, "", config.HaProxyConfig{}, "some-base-config-file", "some-config-file", nil, nil)
 				}).Should(Panic())
 			})
 		})
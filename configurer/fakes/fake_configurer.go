@@ -4,25 +4,34 @@ package fakes
 import (
 	"sync"
 
+	"code.cloudfoundry.org/cf-tcp-router/config"
 	"code.cloudfoundry.org/cf-tcp-router/configurer"
 	"code.cloudfoundry.org/cf-tcp-router/models"
 )
 
 type FakeRouterConfigurer struct {
-	ConfigureStub        func(routingTable models.RoutingTable) error
+	ConfigureStub        func(routingTable models.RoutingTableSnapshot) error
 	configureMutex       sync.RWMutex
 	configureArgsForCall []struct {
-		routingTable models.RoutingTable
+		routingTable models.RoutingTableSnapshot
 	}
 	configureReturns struct {
 		result1 error
 	}
+	UpdatePortOptionsStub        func(portOptions config.PortOptionsConfig) error
+	updatePortOptionsMutex       sync.RWMutex
+	updatePortOptionsArgsForCall []struct {
+		portOptions config.PortOptionsConfig
+	}
+	updatePortOptionsReturns struct {
+		result1 error
+	}
 }
 
-func (fake *FakeRouterConfigurer) Configure(routingTable models.RoutingTable) error {
+func (fake *FakeRouterConfigurer) Configure(routingTable models.RoutingTableSnapshot) error {
 	fake.configureMutex.Lock()
 	fake.configureArgsForCall = append(fake.configureArgsForCall, struct {
-		routingTable models.RoutingTable
+		routingTable models.RoutingTableSnapshot
 	}{routingTable})
 	fake.configureMutex.Unlock()
 	if fake.ConfigureStub != nil {
@@ -38,7 +47,7 @@ func (fake *FakeRouterConfigurer) ConfigureCallCount() int {
 	return len(fake.configureArgsForCall)
 }
 
-func (fake *FakeRouterConfigurer) ConfigureArgsForCall(i int) models.RoutingTable {
+func (fake *FakeRouterConfigurer) ConfigureArgsForCall(i int) models.RoutingTableSnapshot {
 	fake.configureMutex.RLock()
 	defer fake.configureMutex.RUnlock()
 	return fake.configureArgsForCall[i].routingTable
@@ -51,4 +60,36 @@ func (fake *FakeRouterConfigurer) ConfigureReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeRouterConfigurer) UpdatePortOptions(portOptions config.PortOptionsConfig) error {
+	fake.updatePortOptionsMutex.Lock()
+	fake.updatePortOptionsArgsForCall = append(fake.updatePortOptionsArgsForCall, struct {
+		portOptions config.PortOptionsConfig
+	}{portOptions})
+	fake.updatePortOptionsMutex.Unlock()
+	if fake.UpdatePortOptionsStub != nil {
+		return fake.UpdatePortOptionsStub(portOptions)
+	} else {
+		return fake.updatePortOptionsReturns.result1
+	}
+}
+
+func (fake *FakeRouterConfigurer) UpdatePortOptionsCallCount() int {
+	fake.updatePortOptionsMutex.RLock()
+	defer fake.updatePortOptionsMutex.RUnlock()
+	return len(fake.updatePortOptionsArgsForCall)
+}
+
+func (fake *FakeRouterConfigurer) UpdatePortOptionsArgsForCall(i int) config.PortOptionsConfig {
+	fake.updatePortOptionsMutex.RLock()
+	defer fake.updatePortOptionsMutex.RUnlock()
+	return fake.updatePortOptionsArgsForCall[i].portOptions
+}
+
+func (fake *FakeRouterConfigurer) UpdatePortOptionsReturns(result1 error) {
+	fake.UpdatePortOptionsStub = nil
+	fake.updatePortOptionsReturns = struct {
+		result1 error
+	}{result1}
+}
+
 var _ configurer.RouterConfigurer = new(FakeRouterConfigurer)
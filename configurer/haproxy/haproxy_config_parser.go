@@ -0,0 +1,200 @@
+package haproxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cf-tcp-router/models"
+	"code.cloudfoundry.org/lager"
+)
+
+var sectionKeywords = map[string]bool{
+	"global":      true,
+	"defaults":    true,
+	"frontend":    true,
+	"backend":     true,
+	"listen":      true,
+	"userlist":    true,
+	"peers":       true,
+	"resolvers":   true,
+	"mailers":     true,
+	"cache":       true,
+	"program":     true,
+	"http-errors": true,
+	"ring":        true,
+}
+
+// RoutingTableFromConfigFile rebuilds a routing table from a configuration file
+// previously written by the Configurer.
+func RoutingTableFromConfigFile(logger lager.Logger, configFilePath string) (models.RoutingTable, error) {
+	file, err := os.Open(configFilePath)
+	if err != nil {
+		return models.RoutingTable{}, err
+	}
+	defer file.Close()
+
+	return ParseRoutingTable(logger, file)
+}
+
+// ParseRoutingTable reads the listen_cfg_<port> sections generated by the
+// Configurer and returns the routing table they were rendered from. Sections
+// copied from the base configuration, or otherwise not owned by the router,
+// are skipped.
+//
+// Draining backends, rendered with weight 0, are read back as draining since
+// now, so their drain starts over. Sections without route backends are ports
+// that were held down, and are read back as held down since now. Backup and
+// fallback servers come from the port options, which render them again, so
+// they are skipped.
+func ParseRoutingTable(logger lager.Logger, reader io.Reader) (models.RoutingTable, error) {
+	logger = logger.Session("parse-routing-table")
+	routingTable := models.NewRoutingTable(logger)
+
+	var (
+		routingKey models.RoutingKey
+		owned      bool
+	)
+	sections := map[models.RoutingKey][]models.BackendServerInfo{}
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := configLineFields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if sectionKeywords[fields[0]] {
+			routingKey, owned = listenRoutingKey(fields)
+			if _, found := sections[routingKey]; owned && !found {
+				sections[routingKey] = nil
+			}
+			continue
+		}
+
+		if !owned || fields[0] != "server" {
+			continue
+		}
+
+		if len(fields) < 3 {
+			return models.RoutingTable{}, fmt.Errorf("line %d: malformed server line", lineNumber)
+		}
+		if fields[1] == FallbackServerName || backup(fields[3:]) {
+			logger.Debug("skipping-port-options-backend", lager.Data{"key": routingKey, "server": fields[1]})
+			continue
+		}
+		backendServerInfo, err := backendServerInfoFromAddress(fields[2])
+		if err != nil {
+			return models.RoutingTable{}, fmt.Errorf("line %d: %s", lineNumber, err.Error())
+		}
+		backendServerInfo.Draining = draining(fields[3:])
+		sections[routingKey] = append(sections[routingKey], backendServerInfo)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return models.RoutingTable{}, err
+	}
+
+	now := time.Now()
+	for key, backends := range sections {
+		entry := models.RoutingTableEntry{Backends: make(map[models.BackendServerKey]models.BackendServerDetails, len(backends))}
+		for _, backend := range backends {
+			details := models.BackendServerDetails{UpdatedTime: now}
+			if backend.Draining {
+				logger.Debug("draining-backend", lager.Data{"key": key, "info": backend})
+				details.DrainingSince = now
+			}
+			entry.Backends[models.BackendServerKey{Address: backend.Address, Port: backend.Port}] = details
+		}
+		if len(entry.Backends) == 0 {
+			logger.Debug("holding-empty-port", lager.Data{"key": key})
+			entry.EmptySince = now
+		}
+		routingTable.Set(key, entry)
+	}
+
+	logger.Info("parsed-routing-table", lager.Data{"size": routingTable.Size()})
+	return routingTable, nil
+}
+
+// Returns the paths of the stats sockets declared in the global sections.
+func statsSockets(reader io.Reader) ([]string, error) {
+	var (
+		sockets []string
+		global  bool
+	)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := configLineFields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if sectionKeywords[fields[0]] {
+			global = fields[0] == "global"
+			continue
+		}
+		if global && len(fields) >= 3 && fields[0] == "stats" && fields[1] == "socket" {
+			sockets = append(sockets, fields[2])
+		}
+	}
+	return sockets, scanner.Err()
+}
+
+func configLineFields(line string) []string {
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.Fields(line)
+}
+
+func listenRoutingKey(fields []string) (models.RoutingKey, bool) {
+	if fields[0] != "listen" || len(fields) < 2 || !strings.HasPrefix(fields[1], ListenConfigPrefix) {
+		return models.RoutingKey{}, false
+	}
+
+	port, err := strconv.ParseUint(strings.TrimPrefix(fields[1], ListenConfigPrefix), 10, 16)
+	if err != nil || port == 0 {
+		return models.RoutingKey{}, false
+	}
+	return models.RoutingKey{Port: uint16(port)}, true
+}
+
+// Backup backends come from the port options rather than the routing table.
+func backup(serverOptions []string) bool {
+	for _, option := range serverOptions {
+		if option == "backup" {
+			return true
+		}
+	}
+	return false
+}
+
+// Draining backends were deleted and are rendered with weight 0.
+func draining(serverOptions []string) bool {
+	for i := 0; i+1 < len(serverOptions); i++ {
+		if serverOptions[i] == "weight" && serverOptions[i+1] == "0" {
+			return true
+		}
+	}
+	return false
+}
+
+func backendServerInfoFromAddress(address string) (models.BackendServerInfo, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return models.BackendServerInfo{}, err
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 || host == "" {
+		return models.BackendServerInfo{}, fmt.Errorf("invalid server address %s", address)
+	}
+	return models.BackendServerInfo{Address: host, Port: uint16(port)}, nil
+}
@@ -0,0 +1,116 @@
+package haproxy_test
+
+import (
+	"strings"
+
+	"code.cloudfoundry.org/cf-tcp-router/configurer/haproxy"
+	"code.cloudfoundry.org/cf-tcp-router/models"
+	"code.cloudfoundry.org/cf-tcp-router/testutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HaproxyConfigParser", func() {
+	Describe("RoutingTableFromConfigFile", func() {
+		Context("when the config file was generated by the configurer", func() {
+			It("rebuilds the routing table from the listen sections", func() {
+				routingTable, err := haproxy.RoutingTableFromConfigFile(logger, "fixtures/haproxy_generated.cfg")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(routingTable.Size()).To(Equal(2))
+
+				expectedEntry1 := models.NewRoutingTableEntry(
+					[]models.BackendServerInfo{
+						models.BackendServerInfo{Address: "some-ip-1", Port: 1234},
+						models.BackendServerInfo{Address: "some-ip-2", Port: 1235},
+					},
+				)
+				testutil.RoutingTableEntryMatches(routingTable.Get(models.RoutingKey{Port: 2222}), expectedEntry1)
+
+				expectedEntry2 := models.NewRoutingTableEntry(
+					[]models.BackendServerInfo{
+						models.BackendServerInfo{Address: "some-ip-3", Port: 2345},
+					},
+				)
+				testutil.RoutingTableEntryMatches(routingTable.Get(models.RoutingKey{Port: 3333}), expectedEntry2)
+			})
+		})
+
+		Context("when the config file does not exist", func() {
+			It("returns an error", func() {
+				_, err := haproxy.RoutingTableFromConfigFile(logger, "file/path/does/not/exist")
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("ParseRoutingTable", func() {
+		Context("when the config only contains base sections", func() {
+			It("returns an empty routing table", func() {
+				routingTable, err := haproxy.ParseRoutingTable(logger, strings.NewReader("global\n  maxconn 4096\n\ndefaults\n  log global\n"))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(routingTable.Size()).To(Equal(0))
+			})
+		})
+
+		Context("when a listen section has no servers", func() {
+			It("adds the port as held down", func() {
+				routingTable, err := haproxy.ParseRoutingTable(logger, strings.NewReader("listen listen_cfg_2222\n  mode tcp\n  bind :2222\n  tcp-request content reject\n"))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(routingTable.Size()).To(Equal(1))
+				Expect(routingTable.Get(models.RoutingKey{Port: 2222}).HeldDown()).To(BeTrue())
+			})
+		})
+
+		Context("when a listen section name does not carry a valid port", func() {
+			It("skips the section", func() {
+				routingTable, err := haproxy.ParseRoutingTable(logger, strings.NewReader("listen listen_cfg_abc\n  server server_some-ip_1234 some-ip:1234\n"))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(routingTable.Size()).To(Equal(0))
+			})
+		})
+
+		Context("when a server is draining", func() {
+			It("adds the backend as draining", func() {
+				routingTable, err := haproxy.ParseRoutingTable(logger, strings.NewReader("listen listen_cfg_2222\n  server server_some-ip_1234 some-ip:1234 weight 0\n  server server_some-ip_1235 some-ip:1235 weight 5\n"))
+				Expect(err).ShouldNot(HaveOccurred())
+				backends := routingTable.Get(models.RoutingKey{Port: 2222}).Backends
+				Expect(backends).To(HaveLen(2))
+				Expect(backends[models.BackendServerKey{Address: "some-ip", Port: 1234}].Draining()).To(BeTrue())
+				Expect(backends[models.BackendServerKey{Address: "some-ip", Port: 1235}].Draining()).To(BeFalse())
+			})
+		})
+
+		Context("when a held port has a fallback server", func() {
+			It("adds the port as held down without the fallback", func() {
+				routingTable, err := haproxy.ParseRoutingTable(logger, strings.NewReader("listen listen_cfg_2222\n  bind :2222\n  server fallback 10.0.0.9:8080\n"))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(routingTable.Size()).To(Equal(1))
+				Expect(routingTable.Get(models.RoutingKey{Port: 2222}).HeldDown()).To(BeTrue())
+			})
+		})
+
+		Context("when a port has backup servers", func() {
+			It("does not add the backups as backends", func() {
+				routingTable, err := haproxy.ParseRoutingTable(logger, strings.NewReader("listen listen_cfg_2222\n  server server_some-ip_1234 some-ip:1234\n  server backup_10.0.0.8_8080 10.0.0.8:8080 backup\n"))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(routingTable.Get(models.RoutingKey{Port: 2222}).Backends).To(HaveLen(1))
+				Expect(routingTable.Get(models.RoutingKey{Port: 2222}).Backends).To(HaveKey(models.BackendServerKey{Address: "some-ip", Port: 1234}))
+			})
+
+			It("adds a port with only backup servers as held down", func() {
+				routingTable, err := haproxy.ParseRoutingTable(logger, strings.NewReader("listen listen_cfg_2222\n  server backup_10.0.0.8_8080 10.0.0.8:8080 backup\n"))
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(routingTable.Get(models.RoutingKey{Port: 2222}).HeldDown()).To(BeTrue())
+			})
+		})
+
+		Context("when a generated server line is malformed", func() {
+			It("returns an error", func() {
+				_, err := haproxy.ParseRoutingTable(logger, strings.NewReader("listen listen_cfg_2222\n  server server_some-ip_1234 some-ip\n"))
+				Expect(err).Should(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("line 2"))
+			})
+		})
+	})
+})
@@ -3,41 +3,466 @@ package haproxy
 import (
 	"bytes"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"code.cloudfoundry.org/cf-tcp-router/config"
 	"code.cloudfoundry.org/cf-tcp-router/models"
 )
 
-func BackendServerInfoToHaProxyConfig(bs models.BackendServerInfo) (string, error) {
+const (
+	ResolversName      = "tcp_router_dns"
+	PeersName          = "tcp_router_peers"
+	FallbackServerName = "fallback"
+
+	// ListenConfigPrefix starts the names of the listen sections of routed
+	// ports, followed by the port.
+	ListenConfigPrefix = "listen_cfg_"
+)
+
+const (
+	DefaultRateLimitPeriod = 10 * time.Second
+	rateLimitTableSize     = "100k"
+)
+
+func BackendServerInfoToHaProxyConfig(bs models.BackendServerInfo, options config.PortOptions) (string, error) {
+	server, err := backendServerData(bs, options)
+	if err != nil {
+		return "", err
+	}
+	return server.line(), nil
+}
+
+// BackupServerToHaProxyConfig renders a backup backend of the port options.
+func BackupServerToHaProxyConfig(backend string, options config.PortOptions) (string, error) {
+	server, err := backupServerData(backend, options)
+	if err != nil {
+		return "", err
+	}
+	return server.line(), nil
+}
+
+func backendServerData(bs models.BackendServerInfo, options config.PortOptions) (ServerData, error) {
 	if bs.Address == "" {
-		return "", ErrInvalidField{Field: "backend_server.address"}
+		return ServerData{}, ErrInvalidField{Field: "backend_server.address"}
 	}
 	if bs.Port == 0 {
-		return "", ErrInvalidField{Field: "backend_server.port"}
+		return ServerData{}, ErrInvalidField{Field: "backend_server.port"}
 	}
-	name := fmt.Sprintf("server_%s_%d", bs.Address, bs.Port)
-	return fmt.Sprintf("server %s %s:%d\n", name, bs.Address, bs.Port), nil
+	return serverData(ServerName(bs.Address, bs.Port), bs, options, false), nil
 }
 
-func RoutingTableEntryToHaProxyConfig(routingKey models.RoutingKey, routingTableEntry models.RoutingTableEntry) (string, error) {
-	if routingKey.Port == 0 {
-		return "", ErrInvalidField{Field: "listen_configuration.port"}
+func backupServerData(backend string, options config.PortOptions) (ServerData, error) {
+	host, portStr, err := net.SplitHostPort(backend)
+	if err != nil {
+		return ServerData{}, ErrInvalidField{Field: "backup_server.address"}
 	}
-	if len(routingTableEntry.Backends) == 0 {
-		return "", ErrInvalidField{Field: "listen_configuration.backends"}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return ServerData{}, ErrInvalidField{Field: "backup_server.port"}
 	}
-	name := fmt.Sprintf("listen_cfg_%d", routingKey.Port)
+	bs := models.BackendServerInfo{Address: host, Port: uint16(port)}
+	return serverData(BackupServerName(bs.Address, bs.Port), bs, options, true), nil
+}
+
+func serverData(name string, bs models.BackendServerInfo, options config.PortOptions, backup bool) ServerData {
+	return ServerData{
+		Name:     name,
+		Address:  bs.Address,
+		Port:     bs.Port,
+		Endpoint: serverAddress(bs.Address, bs.Port),
+		Weight:   bs.Weight,
+		Draining: bs.Draining,
+		Backup:   backup,
+		Params:   serverParams(bs, options, backup),
+	}
+}
+
+func serverParams(bs models.BackendServerInfo, options config.PortOptions, backup bool) string {
 	var buff bytes.Buffer
+	if bs.Draining {
+		// weight 0 keeps the sessions of the backend but sends it no new ones
+		buff.WriteString(" weight 0")
+	} else if bs.Weight > 0 {
+		buff.WriteString(fmt.Sprintf(" weight %d", bs.Weight))
+	}
+	if backup {
+		buff.WriteString(" backup")
+	}
+	buff.WriteString(serverLimitsToHaProxyConfig(options))
+	if net.ParseIP(bs.Address) == nil {
+		if resolvesAddress(bs.Address, options) {
+			buff.WriteString(fmt.Sprintf(" resolvers %s", ResolversName))
+		}
+		// init-addr none keeps HAProxy starting while a name does not resolve
+		buff.WriteString(" init-addr last,libc,none")
+	}
+	buff.WriteString(healthCheckToHaProxyConfig(options.HealthCheck))
+	buff.WriteString(backendTLSToHaProxyConfig(options.BackendTLS))
+	switch options.SendProxy {
+	case config.SendProxyV1:
+		buff.WriteString(" send-proxy")
+	case config.SendProxyV2:
+		buff.WriteString(" send-proxy-v2")
+	}
+	return buff.String()
+}
+
+// RoutingTableEntryToHaProxyConfig renders the listen section of a port with
+// the built-in listen template.
+func RoutingTableEntryToHaProxyConfig(routingKey models.RoutingKey, routingTableEntry models.RoutingTableEntry, options config.PortOptions) (string, error) {
+	return DefaultListenTemplate().Render(routingKey, routingTableEntry, options)
+}
+
+func listenData(routingKey models.RoutingKey, routingTableEntry models.RoutingTableEntry, options config.PortOptions) (ListenData, error) {
+	if routingKey.Port == 0 {
+		return ListenData{}, ErrInvalidField{Field: "listen_configuration.port"}
+	}
+	if len(routingTableEntry.Backends) == 0 && !routingTableEntry.HeldDown() {
+		return ListenData{}, ErrInvalidField{Field: "listen_configuration.backends"}
+	}
+	data := ListenData{
+		Port:     routingKey.Port,
+		Name:     ListenName(routingKey.Port),
+		Bind:     bindToHaProxyConfig(routingKey.Port, options),
+		Options:  options,
+		Metadata: options.Metadata,
+		HeldDown: routingTableEntry.HeldDown(),
+	}
 
-	buff.WriteString(fmt.Sprintf("listen %s\n  mode tcp\n  bind :%d\n", name, routingKey.Port))
+	rules := portOptionsToHaProxyConfig(options) +
+		sourceACLToHaProxyConfig(options.SourceACL) +
+		rateLimitToHaProxyConfig(options)
+	if data.HeldDown {
+		// A held down port keeps its bind, but has no route backends to send
+		// connections to. Backup backends take them if there are any. The
+		// reject is a content rule, which HAProxy counts in dreq rather than
+		// with the source ACL denies in dcon.
+		if options.FallbackBackend != "" {
+			data.Servers = append(data.Servers, fallbackServerData(options.FallbackBackend))
+		} else if len(options.BackupBackends) == 0 {
+			rules += "  tcp-request content reject\n"
+		}
+	}
+	data.Rules = configLines(rules)
+
+	backends := make([]models.BackendServerInfo, 0, len(routingTableEntry.Backends))
 	for bskey, bsdetails := range routingTableEntry.Backends {
 		bs := models.NewBackendServerInfo(bskey, bsdetails)
-		str, err := BackendServerInfoToHaProxyConfig(bs)
+		if weight, ok := options.BackendWeights[serverAddress(bs.Address, bs.Port)]; ok {
+			bs.Weight = weight
+		}
+		backends = append(backends, bs)
+	}
+	sort.Slice(backends, func(i, j int) bool {
+		if backends[i].Address != backends[j].Address {
+			return backends[i].Address < backends[j].Address
+		}
+		return backends[i].Port < backends[j].Port
+	})
+	for _, bs := range backends {
+		server, err := backendServerData(bs, options)
 		if err != nil {
-			return "", err
+			return ListenData{}, err
 		}
-		buff.WriteString(fmt.Sprintf("  %s", str))
+		data.Servers = append(data.Servers, server)
+	}
+	for _, backend := range options.BackupBackends {
+		server, err := backupServerData(backend, options)
+		if err != nil {
+			return ListenData{}, err
+		}
+		data.Servers = append(data.Servers, server)
+	}
+	return data, nil
+}
+
+func bindToHaProxyConfig(port uint16, options config.PortOptions) string {
+	var buff bytes.Buffer
+	if options.IsDualStack() {
+		buff.WriteString(fmt.Sprintf(":::%d v4v6", port))
+	} else {
+		buff.WriteString(fmt.Sprintf(":%d", port))
+	}
+	if options.TLSCertificate != "" {
+		buff.WriteString(fmt.Sprintf(" ssl crt %s", options.TLSCertificate))
+		if options.ClientCAFile != "" {
+			buff.WriteString(fmt.Sprintf(" verify required ca-file %s", options.ClientCAFile))
+		}
+		if options.ClientCRLFile != "" {
+			buff.WriteString(fmt.Sprintf(" crl-file %s", options.ClientCRLFile))
+		}
+	}
+	if options.AcceptsProxy() {
+		buff.WriteString(" accept-proxy")
+	}
+	return buff.String()
+}
+
+// The fallback backend is validated as "address:port" with the port options.
+func fallbackServerData(backend string) ServerData {
+	server := ServerData{Name: FallbackServerName, Endpoint: backend, Fallback: true}
+	if host, portStr, err := net.SplitHostPort(backend); err == nil {
+		port, _ := strconv.ParseUint(portStr, 10, 16)
+		server.Address, server.Port = host, uint16(port)
+	}
+	return server
+}
+
+// Splits indented configuration lines into the lines without indentation.
+func configLines(section string) []string {
+	if section == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(section, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, "  ")
+	}
+	return lines
+}
+
+// BaseSectionsToHaProxyConfig renders the global and defaults sections.
+func BaseSectionsToHaProxyConfig(haProxyConfig config.HaProxyConfig) string {
+	global := haProxyConfig.Global
+	defaults := haProxyConfig.Defaults
+
+	var buff bytes.Buffer
+	buff.WriteString("global\n")
+	if global.MaxConn != 0 {
+		buff.WriteString(fmt.Sprintf("  maxconn %d\n", global.MaxConn))
+	}
+	if global.NbThread != 0 {
+		buff.WriteString(fmt.Sprintf("  nbthread %d\n", global.NbThread))
+	}
+	if global.StatsSocket != "" {
+		buff.WriteString(fmt.Sprintf("  stats socket %s mode 600 level admin\n", global.StatsSocket))
+	}
+	for _, log := range global.Logs {
+		buff.WriteString(fmt.Sprintf("  log %s\n", log))
+	}
+
+	buff.WriteString("\ndefaults\n")
+	if len(global.Logs) > 0 {
+		buff.WriteString("  log global\n")
+	}
+	if defaults.ConnectTimeout != 0 {
+		buff.WriteString(fmt.Sprintf("  timeout connect %dms\n", defaults.ConnectTimeout/time.Millisecond))
+	}
+	if defaults.ClientTimeout != 0 {
+		buff.WriteString(fmt.Sprintf("  timeout client %dms\n", defaults.ClientTimeout/time.Millisecond))
+	}
+	if defaults.ServerTimeout != 0 {
+		buff.WriteString(fmt.Sprintf("  timeout server %dms\n", defaults.ServerTimeout/time.Millisecond))
+	}
+	if defaults.MaxConn != 0 {
+		buff.WriteString(fmt.Sprintf("  maxconn %d\n", defaults.MaxConn))
+	}
+	return buff.String()
+}
+
+// ResolversToHaProxyConfig renders the resolvers section used by backends
+// that are given as hostnames.
+func ResolversToHaProxyConfig(nameservers []string, holdValid time.Duration) string {
+	var buff bytes.Buffer
+	buff.WriteString(fmt.Sprintf("resolvers %s\n", ResolversName))
+	for i, nameserver := range nameservers {
+		buff.WriteString(fmt.Sprintf("  nameserver dns%d %s\n", i+1, nameserver))
+	}
+	if holdValid != 0 {
+		buff.WriteString(fmt.Sprintf("  hold valid %dms\n", holdValid/time.Millisecond))
+	}
+	return buff.String()
+}
+
+// PeersToHaProxyConfig renders the peers section that stick tables are
+// replicated through.
+func PeersToHaProxyConfig(peers []config.Peer) string {
+	var buff bytes.Buffer
+	buff.WriteString(fmt.Sprintf("peers %s\n", PeersName))
+	for _, peer := range peers {
+		buff.WriteString(fmt.Sprintf("  peer %s %s\n", peer.Name, peer.Address))
+	}
+	return buff.String()
+}
+
+// UsesResolvers reports whether any backend of the entry is resolved at
+// runtime.
+func UsesResolvers(routingTableEntry models.RoutingTableEntry, options config.PortOptions) bool {
+	for bskey := range routingTableEntry.Backends {
+		if resolvesAddress(bskey.Address, options) {
+			return true
+		}
+	}
+	for _, backend := range options.BackupBackends {
+		if host, _, err := net.SplitHostPort(backend); err == nil && resolvesAddress(host, options) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolvesAddress(address string, options config.PortOptions) bool {
+	return len(options.Nameservers) > 0 && net.ParseIP(address) == nil
+}
+
+// ListenName returns the name of the listen section of port.
+func ListenName(port uint16) string {
+	return fmt.Sprintf("%s%d", ListenConfigPrefix, port)
+}
+
+// ServerName returns the name of the server line of a backend. IPv6 addresses
+// are written in canonical form with colons replaced, as HAProxy names may not
+// contain them, so that a backend keeps its name however its address is
+// spelled.
+func ServerName(address string, port uint16) string {
+	return "server_" + serverNameSuffix(address, port)
+}
+
+// BackupServerName returns the name of the server line of a backup backend.
+func BackupServerName(address string, port uint16) string {
+	return "backup_" + serverNameSuffix(address, port)
+}
+
+func serverNameSuffix(address string, port uint16) string {
+	if ip := net.ParseIP(address); ip != nil && ip.To4() == nil {
+		address = strings.Replace(ip.String(), ":", "-", -1)
+	}
+	return fmt.Sprintf("%s_%d", address, port)
+}
+
+// serverAddress brackets IPv6 addresses.
+func serverAddress(address string, port uint16) string {
+	return net.JoinHostPort(address, strconv.Itoa(int(port)))
+}
+
+func portOptionsToHaProxyConfig(options config.PortOptions) string {
+	var buff bytes.Buffer
+	if options.Balance != "" {
+		buff.WriteString(fmt.Sprintf("  balance %s\n", options.Balance))
+		if options.Balance == config.BalanceSource {
+			buff.WriteString("  hash-type consistent\n")
+		}
+	}
+	if options.ClientTimeout != 0 {
+		buff.WriteString(fmt.Sprintf("  timeout client %dms\n", options.ClientTimeout/time.Millisecond))
+	}
+	if options.ServerTimeout != 0 {
+		buff.WriteString(fmt.Sprintf("  timeout server %dms\n", options.ServerTimeout/time.Millisecond))
+	}
+	if options.QueueTimeout != 0 {
+		buff.WriteString(fmt.Sprintf("  timeout queue %dms\n", options.QueueTimeout/time.Millisecond))
+	}
+	if options.MaxConn != 0 {
+		buff.WriteString(fmt.Sprintf("  maxconn %d\n", options.MaxConn))
+	}
+	if options.Retries != 0 {
+		buff.WriteString(fmt.Sprintf("  retries %d\n", options.Retries))
+	}
+	if options.Redispatches() {
+		buff.WriteString("  option redispatch\n")
+	}
+	return buff.String()
+}
+
+func serverLimitsToHaProxyConfig(options config.PortOptions) string {
+	var buff bytes.Buffer
+	if options.ServerMaxConn != 0 {
+		buff.WriteString(fmt.Sprintf(" maxconn %d", options.ServerMaxConn))
+	}
+	if options.ServerMaxQueue != 0 {
+		buff.WriteString(fmt.Sprintf(" maxqueue %d", options.ServerMaxQueue))
+	}
+	if options.SlowStart != 0 {
+		buff.WriteString(fmt.Sprintf(" slowstart %dms", options.SlowStart/time.Millisecond))
+	}
+	return buff.String()
+}
+
+func sourceACLToHaProxyConfig(acl config.SourceACL) string {
+	var buff bytes.Buffer
+	if len(acl.Deny) > 0 {
+		buff.WriteString(fmt.Sprintf("  tcp-request connection reject if { src %s }\n", strings.Join(acl.Deny, " ")))
+	}
+	if len(acl.Allow) > 0 {
+		buff.WriteString(fmt.Sprintf("  tcp-request connection reject if !{ src %s }\n", strings.Join(acl.Allow, " ")))
+	}
+	return buff.String()
+}
+
+// Connections are tracked per source IP in a stick table of the listen section.
+// Sources that stay idle for longer than the rate period expire. Dual stack
+// ports need IPv6 keys, which also hold IPv4 sources. Limited sources are
+// rejected by session rules, which HAProxy counts apart from the connection
+// rules of source ACLs.
+func rateLimitToHaProxyConfig(options config.PortOptions) string {
+	rateLimit := options.RateLimit
+	if !rateLimit.IsEnabled() {
+		return ""
+	}
+	period := rateLimit.Period
+	if period == 0 {
+		period = DefaultRateLimitPeriod
+	}
+	periodMs := period / time.Millisecond
+
+	keyType := "ip"
+	if options.IsDualStack() {
+		keyType = "ipv6"
+	}
+
+	var buff bytes.Buffer
+	buff.WriteString(fmt.Sprintf("  stick-table type %s size %s expire %dms store conn_rate(%dms),conn_cur", keyType, rateLimitTableSize, periodMs, periodMs))
+	if len(options.Peers) > 0 {
+		buff.WriteString(fmt.Sprintf(" peers %s", PeersName))
+	}
+	buff.WriteString("\n")
+	buff.WriteString("  tcp-request connection track-sc0 src\n")
+	if rateLimit.ConnectionRate > 0 {
+		buff.WriteString(fmt.Sprintf("  tcp-request session reject if { sc0_conn_rate gt %d }\n", rateLimit.ConnectionRate))
+	}
+	if rateLimit.MaxConnections > 0 {
+		buff.WriteString(fmt.Sprintf("  tcp-request session reject if { sc0_conn_cur gt %d }\n", rateLimit.MaxConnections))
+	}
+	return buff.String()
+}
+
+func healthCheckToHaProxyConfig(healthCheck config.HealthCheckOptions) string {
+	if !healthCheck.IsEnabled() {
+		return ""
+	}
+	var buff bytes.Buffer
+	buff.WriteString(" check")
+	if healthCheck.Interval != 0 {
+		buff.WriteString(fmt.Sprintf(" inter %dms", healthCheck.Interval/time.Millisecond))
+	}
+	if healthCheck.Rise != 0 {
+		buff.WriteString(fmt.Sprintf(" rise %d", healthCheck.Rise))
+	}
+	if healthCheck.Fall != 0 {
+		buff.WriteString(fmt.Sprintf(" fall %d", healthCheck.Fall))
+	}
+	if healthCheck.ObservesLayer4() {
+		buff.WriteString(" observe layer4 on-error mark-down")
+	}
+	return buff.String()
+}
+
+func backendTLSToHaProxyConfig(backendTLS config.BackendTLSOptions) string {
+	if !backendTLS.IsEnabled() {
+		return ""
+	}
+	var buff bytes.Buffer
+	buff.WriteString(fmt.Sprintf(" ssl verify required ca-file %s", backendTLS.CAFile))
+	if backendTLS.SNI != "" {
+		buff.WriteString(fmt.Sprintf(" sni str(%s)", backendTLS.SNI))
+	}
+	if backendTLS.ClientCertificate != "" {
+		buff.WriteString(fmt.Sprintf(" crt %s", backendTLS.ClientCertificate))
 	}
-	return buff.String(), nil
+	return buff.String()
 }
 
 type ErrInvalidField struct {
@@ -1,6 +1,9 @@
 package haproxy_test
 
 import (
+	"time"
+
+	"code.cloudfoundry.org/cf-tcp-router/config"
 	"code.cloudfoundry.org/cf-tcp-router/configurer/haproxy"
 	"code.cloudfoundry.org/cf-tcp-router/models"
 
@@ -13,9 +16,155 @@ var _ = Describe("HaproxyConfiguration", func() {
 		Context("when configuration is valid", func() {
 			It("returns a valid haproxy configuration representation", func() {
 				bs := models.BackendServerInfo{Address: "some-ip", Port: 1234}
-				str, err := haproxy.BackendServerInfoToHaProxyConfig(bs)
+				str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, config.PortOptions{})
 				Expect(err).ShouldNot(HaveOccurred())
-				Expect(str).Should(Equal("server server_some-ip_1234 some-ip:1234\n"))
+				Expect(str).Should(Equal("server server_some-ip_1234 some-ip:1234 init-addr last,libc,none\n"))
+			})
+
+			Context("when the address is IPv6", func() {
+				It("brackets the address and removes colons from the server name", func() {
+					bs := models.BackendServerInfo{Address: "fd00::1", Port: 1234}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, config.PortOptions{})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_fd00--1_1234 [fd00::1]:1234\n"))
+				})
+
+				It("names the server the same however the address is written", func() {
+					Expect(haproxy.ServerName("FD00:0:0::1", 1234)).To(Equal(haproxy.ServerName("fd00::1", 1234)))
+				})
+
+				It("leaves IPv4 server names unchanged", func() {
+					Expect(haproxy.ServerName("10.0.0.1", 1234)).To(Equal("server_10.0.0.1_1234"))
+				})
+			})
+
+			Context("when nameservers are not configured", func() {
+				It("starts hostname backends without an address when they do not resolve", func() {
+					bs := models.BackendServerInfo{Address: "db.service.internal", Port: 5432}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, config.PortOptions{})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_db.service.internal_5432 db.service.internal:5432 init-addr last,libc,none\n"))
+				})
+			})
+
+			Context("when nameservers are configured", func() {
+				var options config.PortOptions
+
+				BeforeEach(func() {
+					options = config.PortOptions{Nameservers: []string{"10.0.0.2:53"}}
+				})
+
+				It("resolves hostname backends at runtime", func() {
+					bs := models.BackendServerInfo{Address: "db.service.internal", Port: 5432}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_db.service.internal_5432 db.service.internal:5432 resolvers tcp_router_dns init-addr last,libc,none\n"))
+				})
+
+				It("does not resolve IP backends", func() {
+					bs := models.BackendServerInfo{Address: "10.0.0.1", Port: 5432}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_10.0.0.1_5432 10.0.0.1:5432\n"))
+				})
+			})
+
+			Context("when a weight is provided", func() {
+				It("renders the weight", func() {
+					bs := models.BackendServerInfo{Address: "some-ip", Port: 1234, Weight: 20}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, config.PortOptions{})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_some-ip_1234 some-ip:1234 weight 20 init-addr last,libc,none\n"))
+				})
+			})
+
+			Context("when the backend is draining", func() {
+				It("renders weight 0", func() {
+					bs := models.BackendServerInfo{Address: "some-ip", Port: 1234, Weight: 20, Draining: true}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, config.PortOptions{})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_some-ip_1234 some-ip:1234 weight 0 init-addr last,libc,none\n"))
+				})
+			})
+
+			Context("when health checks are enabled", func() {
+				var enabled bool
+
+				BeforeEach(func() {
+					enabled = true
+				})
+
+				It("renders the check parameters", func() {
+					bs := models.BackendServerInfo{Address: "some-ip", Port: 1234}
+					options := config.PortOptions{HealthCheck: config.HealthCheckOptions{
+						Enabled:       &enabled,
+						Interval:      2 * time.Second,
+						Rise:          2,
+						Fall:          3,
+						ObserveLayer4: &enabled,
+					}}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_some-ip_1234 some-ip:1234 init-addr last,libc,none check inter 2000ms rise 2 fall 3 observe layer4 on-error mark-down\n"))
+				})
+
+				It("leaves unset parameters to HAProxy", func() {
+					bs := models.BackendServerInfo{Address: "some-ip", Port: 1234}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, config.PortOptions{HealthCheck: config.HealthCheckOptions{Enabled: &enabled}})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_some-ip_1234 some-ip:1234 init-addr last,libc,none check\n"))
+				})
+
+				It("does not render checks once disabled", func() {
+					disabled := false
+					bs := models.BackendServerInfo{Address: "some-ip", Port: 1234}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, config.PortOptions{HealthCheck: config.HealthCheckOptions{Enabled: &disabled, Rise: 2}})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_some-ip_1234 some-ip:1234 init-addr last,libc,none\n"))
+				})
+			})
+
+			Context("when the PROXY protocol is sent to backends", func() {
+				It("renders the PROXY protocol version", func() {
+					bs := models.BackendServerInfo{Address: "some-ip", Port: 1234}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, config.PortOptions{SendProxy: config.SendProxyV1})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_some-ip_1234 some-ip:1234 init-addr last,libc,none send-proxy\n"))
+				})
+
+				It("renders the PROXY protocol version after the checks", func() {
+					enabled := true
+					bs := models.BackendServerInfo{Address: "some-ip", Port: 1234}
+					options := config.PortOptions{SendProxy: config.SendProxyV2, HealthCheck: config.HealthCheckOptions{Enabled: &enabled}}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_some-ip_1234 some-ip:1234 init-addr last,libc,none check send-proxy-v2\n"))
+				})
+			})
+
+			Context("when backends are re-encrypted", func() {
+				It("re-encrypts to backends with verification, SNI and a client certificate", func() {
+					enabled := true
+					bs := models.BackendServerInfo{Address: "some-ip", Port: 1234}
+					options := config.PortOptions{BackendTLS: config.BackendTLSOptions{
+						Enabled:           &enabled,
+						CAFile:            "/path/to/ca.pem",
+						SNI:               "app.internal",
+						ClientCertificate: "/path/to/client.pem",
+					}}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_some-ip_1234 some-ip:1234 init-addr last,libc,none ssl verify required ca-file /path/to/ca.pem sni str(app.internal) crt /path/to/client.pem\n"))
+				})
+
+				It("verifies the backends against the CA only", func() {
+					enabled := true
+					bs := models.BackendServerInfo{Address: "10.0.0.1", Port: 1234}
+					options := config.PortOptions{BackendTLS: config.BackendTLSOptions{Enabled: &enabled, CAFile: "/path/to/ca.pem"}}
+					str, err := haproxy.BackendServerInfoToHaProxyConfig(bs, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("server server_10.0.0.1_1234 10.0.0.1:1234 ssl verify required ca-file /path/to/ca.pem\n"))
+				})
 			})
 		})
 
@@ -23,7 +172,7 @@ var _ = Describe("HaproxyConfiguration", func() {
 			Context("when address is empty", func() {
 				It("returns an error", func() {
 					bs := models.BackendServerInfo{Address: "", Port: 1234}
-					_, err := haproxy.BackendServerInfoToHaProxyConfig(bs)
+					_, err := haproxy.BackendServerInfoToHaProxyConfig(bs, config.PortOptions{})
 					Expect(err).Should(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("backend_server.address"))
 				})
@@ -32,7 +181,7 @@ var _ = Describe("HaproxyConfiguration", func() {
 			Context("when port is invalid", func() {
 				It("returns an error", func() {
 					bs := models.BackendServerInfo{Address: "some-ip", Port: 0}
-					_, err := haproxy.BackendServerInfoToHaProxyConfig(bs)
+					_, err := haproxy.BackendServerInfoToHaProxyConfig(bs, config.PortOptions{})
 					Expect(err).Should(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("backend_server.port"))
 				})
@@ -40,6 +189,47 @@ var _ = Describe("HaproxyConfiguration", func() {
 		})
 	})
 
+	Describe("BaseSectionsToHaProxyConfig", func() {
+		It("renders the global and defaults sections", func() {
+			str := haproxy.BaseSectionsToHaProxyConfig(config.HaProxyConfig{
+				Enabled: true,
+				Global: config.HaProxyGlobalConfig{
+					MaxConn:     4096,
+					NbThread:    4,
+					StatsSocket: "/var/vcap/sys/run/haproxy/stats.sock",
+					Logs:        []string{"127.0.0.1:514 local0 info"},
+				},
+				Defaults: config.HaProxyDefaultsConfig{
+					ConnectTimeout: 5 * time.Second,
+					ClientTimeout:  5 * time.Minute,
+					ServerTimeout:  5 * time.Minute,
+					MaxConn:        2000,
+				},
+			})
+			Expect(str).Should(Equal("global\n  maxconn 4096\n  nbthread 4\n" +
+				"  stats socket /var/vcap/sys/run/haproxy/stats.sock mode 600 level admin\n" +
+				"  log 127.0.0.1:514 local0 info\n" +
+				"\ndefaults\n  log global\n  timeout connect 5000ms\n  timeout client 300000ms\n  timeout server 300000ms\n  maxconn 2000\n"))
+		})
+	})
+
+	Describe("ResolversToHaProxyConfig", func() {
+		It("lists the nameservers and hold period", func() {
+			str := haproxy.ResolversToHaProxyConfig([]string{"10.0.0.2:53", "[fd00::53]:53"}, 30*time.Second)
+			Expect(str).Should(Equal("resolvers tcp_router_dns\n  nameserver dns1 10.0.0.2:53\n  nameserver dns2 [fd00::53]:53\n  hold valid 30000ms\n"))
+		})
+	})
+
+	Describe("PeersToHaProxyConfig", func() {
+		It("lists the peers", func() {
+			str := haproxy.PeersToHaProxyConfig([]config.Peer{
+				{Name: "tcp-router-0", Address: "10.0.16.10:10000"},
+				{Name: "tcp-router-1", Address: "10.0.16.11:10000"},
+			})
+			Expect(str).Should(Equal("peers tcp_router_peers\n  peer tcp-router-0 10.0.16.10:10000\n  peer tcp-router-1 10.0.16.11:10000\n"))
+		})
+	})
+
 	Describe("RoutingTableEntryToHaProxyConfig", func() {
 		Context("when configuration is valid", func() {
 			Context("when single backend server info is provided", func() {
@@ -50,9 +240,9 @@ var _ = Describe("HaproxyConfiguration", func() {
 							models.BackendServerKey{Address: "some-ip", Port: 1234}: models.BackendServerDetails{},
 						},
 					}
-					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry)
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, config.PortOptions{})
 					Expect(err).ShouldNot(HaveOccurred())
-					Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n  server server_some-ip_1234 some-ip:1234\n"))
+					Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n  server server_some-ip_1234 some-ip:1234 init-addr last,libc,none\n"))
 				})
 			})
 
@@ -65,11 +255,224 @@ var _ = Describe("HaproxyConfiguration", func() {
 							models.BackendServerKey{Address: "some-ip-2", Port: 1235}: models.BackendServerDetails{},
 						},
 					}
-					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry)
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, config.PortOptions{})
 					Expect(err).ShouldNot(HaveOccurred())
 					Expect(str).Should(ContainSubstring("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n"))
-					Expect(str).Should(ContainSubstring("server server_some-ip-1_1234 some-ip-1:1234\n"))
-					Expect(str).Should(ContainSubstring("server server_some-ip-2_1235 some-ip-2:1235\n"))
+					Expect(str).Should(ContainSubstring("server server_some-ip-1_1234 some-ip-1:1234 init-addr last,libc,none\n"))
+					Expect(str).Should(ContainSubstring("server server_some-ip-2_1235 some-ip-2:1235 init-addr last,libc,none\n"))
+				})
+			})
+
+			Context("when port options are provided", func() {
+				var (
+					routingKey        models.RoutingKey
+					routingTableEntry models.RoutingTableEntry
+				)
+
+				BeforeEach(func() {
+					routingKey = models.RoutingKey{Port: 8880}
+					routingTableEntry = models.RoutingTableEntry{
+						Backends: map[models.BackendServerKey]models.BackendServerDetails{
+							models.BackendServerKey{Address: "some-ip", Port: 1234}: models.BackendServerDetails{},
+						},
+					}
+				})
+
+				It("renders the options after the bind line", func() {
+					options := config.PortOptions{
+						Balance:       config.BalanceLeastConn,
+						ClientTimeout: 30 * time.Second,
+						ServerTimeout: time.Minute,
+						MaxConn:       100,
+					}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n" +
+						"  balance leastconn\n  timeout client 30000ms\n  timeout server 60000ms\n  maxconn 100\n" +
+						"  server server_some-ip_1234 some-ip:1234 init-addr last,libc,none\n"))
+				})
+
+				It("queues connections and ramps up servers with slow start", func() {
+					redispatch := true
+					options := config.PortOptions{
+						QueueTimeout:   5 * time.Second,
+						Retries:        3,
+						Redispatch:     &redispatch,
+						SlowStart:      30 * time.Second,
+						ServerMaxConn:  50,
+						ServerMaxQueue: 10,
+					}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n" +
+						"  timeout queue 5000ms\n  retries 3\n  option redispatch\n" +
+						"  server server_some-ip_1234 some-ip:1234 maxconn 50 maxqueue 10 slowstart 30000ms init-addr last,libc,none\n"))
+				})
+
+				It("uses consistent hashing for the source algorithm", func() {
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, config.PortOptions{Balance: config.BalanceSource})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(ContainSubstring("  balance source\n  hash-type consistent\n"))
+				})
+
+				It("accepts the PROXY protocol on the bind line and sends it to backends", func() {
+					acceptProxy := true
+					options := config.PortOptions{AcceptProxy: &acceptProxy, SendProxy: config.SendProxyV1}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880 accept-proxy\n" +
+						"  server server_some-ip_1234 some-ip:1234 init-addr last,libc,none send-proxy\n"))
+				})
+
+				It("terminates TLS with the certificate of the port", func() {
+					options := config.PortOptions{TLSCertificate: "/path/to/certs/tcp_router.pem"}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(ContainSubstring("  bind :8880 ssl crt /path/to/certs/tcp_router.pem\n"))
+				})
+
+				It("requires client certificates signed by the client CA", func() {
+					options := config.PortOptions{
+						TLSCertificate: "/path/to/certs/tcp_router.pem",
+						ClientCAFile:   "/path/to/certs/client_ca.pem",
+						ClientCRLFile:  "/path/to/certs/client_ca.crl",
+					}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(ContainSubstring("  bind :8880 ssl crt /path/to/certs/tcp_router.pem " +
+						"verify required ca-file /path/to/certs/client_ca.pem crl-file /path/to/certs/client_ca.crl\n"))
+				})
+
+				It("rejects connections from denied or not allowed sources", func() {
+					options := config.PortOptions{MaxConn: 10, SourceACL: config.SourceACL{
+						Allow: []string{"10.0.0.0/8", "192.168.1.10"},
+						Deny:  []string{"10.1.0.0/16"},
+					}}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n  maxconn 10\n" +
+						"  tcp-request connection reject if { src 10.1.0.0/16 }\n" +
+						"  tcp-request connection reject if !{ src 10.0.0.0/8 192.168.1.10 }\n" +
+						"  server server_some-ip_1234 some-ip:1234 init-addr last,libc,none\n"))
+				})
+
+				It("tracks sources in a stick table and rejects them over the rate limit", func() {
+					options := config.PortOptions{RateLimit: config.RateLimitOptions{ConnectionRate: 100, MaxConnections: 20}}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n" +
+						"  stick-table type ip size 100k expire 10000ms store conn_rate(10000ms),conn_cur\n" +
+						"  tcp-request connection track-sc0 src\n" +
+						"  tcp-request session reject if { sc0_conn_rate gt 100 }\n" +
+						"  tcp-request session reject if { sc0_conn_cur gt 20 }\n" +
+						"  server server_some-ip_1234 some-ip:1234 init-addr last,libc,none\n"))
+				})
+
+				It("tracks IPv6 sources with the rate period of the options on dual stack ports", func() {
+					dualStack := true
+					options := config.PortOptions{DualStack: &dualStack, RateLimit: config.RateLimitOptions{MaxConnections: 5, Period: time.Minute}}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(ContainSubstring("  stick-table type ipv6 size 100k expire 60000ms store conn_rate(60000ms),conn_cur\n"))
+					Expect(str).ShouldNot(ContainSubstring("sc0_conn_rate"))
+					Expect(str).Should(ContainSubstring("  tcp-request session reject if { sc0_conn_cur gt 5 }\n"))
+				})
+
+				It("replicates the stick table to the peers", func() {
+					options := config.PortOptions{
+						RateLimit: config.RateLimitOptions{ConnectionRate: 100},
+						Peers:     []config.Peer{{Name: "tcp-router-0", Address: "10.0.16.10:10000"}},
+					}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(ContainSubstring("  stick-table type ip size 100k expire 10000ms store conn_rate(10000ms),conn_cur peers tcp_router_peers\n"))
+				})
+
+				It("binds on IPv6 and IPv4 when the port is dual stack", func() {
+					dualStack := true
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, config.PortOptions{DualStack: &dualStack})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(ContainSubstring("  bind :::8880 v4v6\n"))
+				})
+
+				It("overrides the weight of IPv6 backends by bracketed address", func() {
+					routingTableEntry.Backends = map[models.BackendServerKey]models.BackendServerDetails{
+						{Address: "fd00::1", Port: 1234}: {},
+					}
+					options := config.PortOptions{BackendWeights: map[string]int{"[fd00::1]:1234": 20}}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(ContainSubstring("  server server_fd00--1_1234 [fd00::1]:1234 weight 20\n"))
+				})
+
+				It("does not send the PROXY protocol when it is turned off", func() {
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, config.PortOptions{SendProxy: config.SendProxyOff})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).ShouldNot(ContainSubstring("send-proxy"))
+				})
+
+				It("adds the backup backends after the route backends", func() {
+					options := config.PortOptions{
+						BackupBackends: []string{"maintenance.internal:8080", "[fd00::9]:8080"},
+						SendProxy:      config.SendProxyV1,
+					}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(HaveSuffix(
+						"  server server_some-ip_1234 some-ip:1234 init-addr last,libc,none send-proxy\n" +
+							"  server backup_maintenance.internal_8080 maintenance.internal:8080 backup init-addr last,libc,none send-proxy\n" +
+							"  server backup_fd00--9_8080 [fd00::9]:8080 backup send-proxy\n",
+					))
+				})
+
+				It("resolves backup backends given as hostnames", func() {
+					options := config.PortOptions{BackupBackends: []string{"maintenance.internal:8080"}, Nameservers: []string{"10.0.0.2:53"}}
+					Expect(haproxy.UsesResolvers(routingTableEntry, options)).To(BeTrue())
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(ContainSubstring("  server backup_maintenance.internal_8080 maintenance.internal:8080 backup resolvers tcp_router_dns init-addr last,libc,none\n"))
+				})
+
+				It("overrides the weight of backends listed in the options", func() {
+					routingTableEntry.Backends[models.BackendServerKey{Address: "some-ip-2", Port: 1235}] = models.BackendServerDetails{Weight: 5}
+					options := config.PortOptions{BackendWeights: map[string]int{"some-ip:1234": 50}}
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, options)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(ContainSubstring("  server server_some-ip_1234 some-ip:1234 weight 50 init-addr last,libc,none\n"))
+					Expect(str).Should(ContainSubstring("  server server_some-ip-2_1235 some-ip-2:1235 weight 5 init-addr last,libc,none\n"))
+				})
+			})
+
+			Context("when the port is held down without backends", func() {
+				var (
+					routingKey        models.RoutingKey
+					routingTableEntry models.RoutingTableEntry
+				)
+
+				BeforeEach(func() {
+					routingKey = models.RoutingKey{Port: 8880}
+					routingTableEntry = models.RoutingTableEntry{
+						Backends:   map[models.BackendServerKey]models.BackendServerDetails{},
+						EmptySince: time.Now(),
+					}
+				})
+
+				It("keeps the port bound and rejects connections", func() {
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, config.PortOptions{})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n  tcp-request content reject\n"))
+				})
+
+				It("leaves connections to the backup backends", func() {
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, config.PortOptions{BackupBackends: []string{"10.0.0.8:8080"}})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n  server backup_10.0.0.8_8080 10.0.0.8:8080 backup\n"))
+				})
+
+				It("sends connections to the fallback backend", func() {
+					str, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, config.PortOptions{FallbackBackend: "10.0.0.9:8080"})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n  server fallback 10.0.0.9:8080\n"))
 				})
 			})
 		})
@@ -83,7 +486,7 @@ var _ = Describe("HaproxyConfiguration", func() {
 							models.BackendServerKey{Address: "some-ip", Port: 1234}: models.BackendServerDetails{},
 						},
 					}
-					_, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry)
+					_, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, config.PortOptions{})
 					Expect(err).Should(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("listen_configuration.port"))
 				})
@@ -97,7 +500,7 @@ var _ = Describe("HaproxyConfiguration", func() {
 							models.BackendServerKey{Address: "", Port: 1234}: models.BackendServerDetails{},
 						},
 					}
-					_, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry)
+					_, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, config.PortOptions{})
 					Expect(err).Should(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("backend_server.address"))
 				})
@@ -109,7 +512,7 @@ var _ = Describe("HaproxyConfiguration", func() {
 					routingTableEntry := models.RoutingTableEntry{
 						Backends: map[models.BackendServerKey]models.BackendServerDetails{},
 					}
-					_, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry)
+					_, err := haproxy.RoutingTableEntryToHaProxyConfig(routingKey, routingTableEntry, config.PortOptions{})
 					Expect(err).Should(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("listen_configuration.backends"))
 				})
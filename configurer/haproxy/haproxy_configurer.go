@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"sync"
+	"time"
 
+	"code.cloudfoundry.org/cf-tcp-router/config"
 	"code.cloudfoundry.org/cf-tcp-router/models"
 	"code.cloudfoundry.org/cf-tcp-router/monitor"
 	"code.cloudfoundry.org/cf-tcp-router/utils"
@@ -19,69 +22,175 @@ const (
 
 type Configurer struct {
 	logger             lager.Logger
+	baseSections       []byte
+	listenTemplate     ListenTemplate
 	baseConfigFilePath string
 	configFilePath     string
 	configFileLock     *sync.Mutex
 	monitor            monitor.Monitor
 	scriptRunner       ScriptRunner
+	baseConfig         baseConfig
+	fragments          map[models.RoutingKey]listenFragment
+	portOptions        config.PortOptionsConfig
+	lastRoutingTable   *models.RoutingTableSnapshot
 }
 
-func NewHaProxyConfigurer(logger lager.Logger, baseConfigFilePath string, configFilePath string, monitor monitor.Monitor, scriptRunner ScriptRunner) (*Configurer, error) {
-	if !utils.FileExists(baseConfigFilePath) {
+// The base configuration is only re-read when the file changes.
+type baseConfig struct {
+	modTime time.Time
+	size    int64
+	content []byte
+}
+
+// A rendered listen section, valid for as long as the generation of its port
+// is unchanged.
+type listenFragment struct {
+	generation uint64
+	content    []byte
+	resolves   bool
+}
+
+// NewHaProxyConfigurer returns a Configurer that starts every configuration
+// with the base sections, if enabled, followed by the base configuration
+// file. The file may only be omitted while the base sections are enabled.
+// Listen sections are rendered with the configured listen template, if any.
+func NewHaProxyConfigurer(logger lager.Logger, baseSections config.HaProxyConfig, baseConfigFilePath string, configFilePath string, monitor monitor.Monitor, scriptRunner ScriptRunner) (*Configurer, error) {
+	if (baseConfigFilePath != "" || !baseSections.Enabled) && !utils.FileExists(baseConfigFilePath) {
 		return nil, fmt.Errorf("%s: [%s]", ErrRouterConfigFileNotFound, baseConfigFilePath)
 	}
 	if !utils.FileExists(configFilePath) {
 		return nil, fmt.Errorf("%s: [%s]", ErrRouterConfigFileNotFound, configFilePath)
 	}
+	var generated []byte
+	if baseSections.Enabled {
+		generated = []byte(BaseSectionsToHaProxyConfig(baseSections))
+	}
+	listenTemplate := DefaultListenTemplate()
+	if baseSections.ListenTemplate != "" {
+		var err error
+		listenTemplate, err = LoadListenTemplate(logger, baseSections.ListenTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &Configurer{
 		logger:             logger,
+		baseSections:       generated,
+		listenTemplate:     listenTemplate,
 		baseConfigFilePath: baseConfigFilePath,
 		configFilePath:     configFilePath,
 		configFileLock:     new(sync.Mutex),
 		monitor:            monitor,
 		scriptRunner:       scriptRunner,
+		fragments:          make(map[models.RoutingKey]listenFragment),
 	}, nil
 }
 
-func (h *Configurer) Configure(routingTable models.RoutingTable) error {
+func (h *Configurer) Configure(routingTable models.RoutingTableSnapshot) error {
 	h.monitor.StopWatching()
 	h.configFileLock.Lock()
 	defer h.configFileLock.Unlock()
 
+	return h.configure(routingTable)
+}
+
+// UpdatePortOptions replaces the port options and, if a routing table has
+// already been configured, rewrites the configuration with the new options.
+func (h *Configurer) UpdatePortOptions(portOptions config.PortOptionsConfig) error {
+	err := portOptions.ValidateFiles()
+	if err != nil {
+		h.logger.Error("invalid-port-options", err)
+		return err
+	}
+
+	h.configFileLock.Lock()
+	defer h.configFileLock.Unlock()
+
+	h.portOptions = portOptions
+	h.fragments = make(map[models.RoutingKey]listenFragment)
+	if h.lastRoutingTable == nil {
+		return nil
+	}
+
+	h.logger.Info("reconfiguring-with-port-options")
+	h.monitor.StopWatching()
+	return h.configure(*h.lastRoutingTable)
+}
+
+// Only listen sections whose port generation changed are rendered; the others
+// are reused from the fragment cache. HAProxy reads the whole file on reload,
+// so assembling it still walks, sorts and writes every port.
+func (h *Configurer) configure(routingTable models.RoutingTableSnapshot) error {
 	err := h.createConfigBackup()
 	if err != nil {
 		return err
 	}
 
-	cfgContent, err := ioutil.ReadFile(h.baseConfigFilePath)
+	cfgContent, err := h.readBaseConfig()
 	if err != nil {
 		h.logger.Error("failed-reading-base-config-file", err, lager.Data{"base-config-file": h.baseConfigFilePath})
 		return err
 	}
 	var buff bytes.Buffer
+	if h.baseSections != nil {
+		buff.Write(h.baseSections)
+		if len(cfgContent) > 0 {
+			buff.WriteString("\n")
+		}
+	}
 	_, err = buff.Write(cfgContent)
 	if err != nil {
 		h.logger.Error("failed-copying-config-file", err, lager.Data{"config-file": h.configFilePath})
 		return err
 	}
 
-	for key, entry := range routingTable.Entries {
-		cfgContent, err = h.getListenConfiguration(key, entry)
+	keys := make([]models.RoutingKey, 0, len(routingTable.Entries))
+	for key := range routingTable.Entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Port < keys[j].Port })
+
+	rendered := 0
+	resolves := false
+	var listenBuff bytes.Buffer
+	for _, key := range keys {
+		fragment, err := h.cachedListenConfiguration(key, routingTable, &rendered)
 		if err != nil {
 			continue
 		}
-		_, err = buff.Write(cfgContent)
+		resolves = resolves || fragment.resolves
+		_, err = listenBuff.Write(fragment.content)
 		if err != nil {
 			h.logger.Error("failed-writing-to-buffer", err)
 			return err
 		}
 	}
+	if resolves {
+		buff.WriteString("\n")
+		buff.WriteString(ResolversToHaProxyConfig(h.portOptions.Defaults.Nameservers, h.portOptions.Defaults.HoldValid))
+	}
+	if len(h.portOptions.Peers) > 0 {
+		buff.WriteString("\n")
+		buff.WriteString(PeersToHaProxyConfig(h.portOptions.Peers))
+	}
+	_, err = buff.Write(listenBuff.Bytes())
+	if err != nil {
+		h.logger.Error("failed-writing-to-buffer", err)
+		return err
+	}
+	for key := range h.fragments {
+		if _, found := routingTable.Entries[key]; !found {
+			delete(h.fragments, key)
+		}
+	}
+	h.logger.Debug("rendered-listen-configurations", lager.Data{"rendered": rendered, "reused": len(keys) - rendered})
 
 	h.logger.Info("writing-config", lager.Data{"num-bytes": buff.Len()})
 	err = h.writeToConfig(buff.Bytes())
 	if err != nil {
 		return err
 	}
+	h.lastRoutingTable = &routingTable
 
 	if h.scriptRunner != nil {
 		h.logger.Info("running-script")
@@ -96,6 +205,52 @@ func (h *Configurer) Configure(routingTable models.RoutingTable) error {
 	return nil
 }
 
+// Returns the listen configuration for key, rendering it only if the cached
+// fragment is from a different generation. Generation 0 is never cached.
+func (h *Configurer) cachedListenConfiguration(key models.RoutingKey, routingTable models.RoutingTableSnapshot, rendered *int) (listenFragment, error) {
+	generation := routingTable.Generation(key)
+	if fragment, found := h.fragments[key]; found && generation != 0 && fragment.generation == generation {
+		return fragment, nil
+	}
+
+	*rendered++
+	entry := routingTable.Entries[key]
+	cfgContent, err := h.getListenConfiguration(key, entry)
+	if err != nil {
+		delete(h.fragments, key)
+		return listenFragment{}, err
+	}
+	fragment := listenFragment{
+		generation: generation,
+		content:    cfgContent,
+		resolves:   UsesResolvers(entry, h.portOptions.For(key.Port)),
+	}
+	if generation != 0 {
+		h.fragments[key] = fragment
+	}
+	return fragment, nil
+}
+
+func (h *Configurer) readBaseConfig() ([]byte, error) {
+	if h.baseConfigFilePath == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(h.baseConfigFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if h.baseConfig.content != nil && info.ModTime().Equal(h.baseConfig.modTime) && info.Size() == h.baseConfig.size {
+		return h.baseConfig.content, nil
+	}
+
+	cfgContent, err := ioutil.ReadFile(h.baseConfigFilePath)
+	if err != nil {
+		return nil, err
+	}
+	h.baseConfig = baseConfig{modTime: info.ModTime(), size: info.Size(), content: cfgContent}
+	return cfgContent, nil
+}
+
 func (h *Configurer) getListenConfiguration(key models.RoutingKey, entry models.RoutingTableEntry) ([]byte, error) {
 	var buff bytes.Buffer
 	_, err := buff.WriteString("\n")
@@ -105,7 +260,7 @@ func (h *Configurer) getListenConfiguration(key models.RoutingKey, entry models.
 	}
 
 	var listenCfgStr string
-	listenCfgStr, err = RoutingTableEntryToHaProxyConfig(key, entry)
+	listenCfgStr, err = h.listenTemplate.Render(key, entry, h.portOptions.For(key.Port))
 	if err != nil {
 		h.logger.Error("failed-marshaling-routing-table-entry", err)
 		return nil, err
@@ -153,3 +308,39 @@ func (h *Configurer) writeToConfig(cfgContent []byte) error {
 	}
 	return nil
 }
+
+// CheckStatsSocket returns an error if the configuration would not declare
+// statsSocket as its stats socket, which the router collects metrics from.
+// Generated base sections must be the only place to declare it.
+func CheckStatsSocket(baseSections config.HaProxyConfig, baseConfigFilePath string, statsSocket string) error {
+	var declared []string
+	if baseConfigFilePath != "" {
+		file, err := os.Open(baseConfigFilePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		declared, err = statsSockets(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	if baseSections.Enabled {
+		if baseSections.Global.StatsSocket != statsSocket {
+			return fmt.Errorf("stats socket %s does not match %s", baseSections.Global.StatsSocket, statsSocket)
+		}
+		if len(declared) > 0 {
+			return fmt.Errorf("base configuration declares stats socket %s, which is generated", declared[0])
+		}
+		return nil
+	}
+
+	for _, socket := range declared {
+		if socket == statsSocket {
+			return nil
+		}
+	}
+	return fmt.Errorf("base configuration does not declare stats socket %s", statsSocket)
+}
@@ -0,0 +1,127 @@
+package haproxy_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.cloudfoundry.org/cf-tcp-router/config"
+	"code.cloudfoundry.org/cf-tcp-router/configurer/haproxy"
+	"code.cloudfoundry.org/cf-tcp-router/models"
+	monitorFakes "code.cloudfoundry.org/cf-tcp-router/monitor/fakes"
+	"code.cloudfoundry.org/lager"
+	routing_api_models "code.cloudfoundry.org/routing-api/models"
+)
+
+const (
+	benchmarkPorts           = 1000
+	benchmarkBackendsPerPort = 100
+)
+
+// Records the number of listen sections each Configure call rendered.
+type renderedSink struct {
+	rendered []int
+}
+
+func (s *renderedSink) Log(log lager.LogFormat) {
+	if strings.HasSuffix(log.Message, "rendered-listen-configurations") {
+		if rendered, ok := log.Data["rendered"].(int); ok {
+			s.rendered = append(s.rendered, rendered)
+		}
+	}
+}
+
+func benchmarkConfigurer(b *testing.B, logger lager.Logger) (*haproxy.Configurer, func()) {
+	dir, err := ioutil.TempDir("", "haproxy-benchmark")
+	if err != nil {
+		b.Fatal(err)
+	}
+	baseConfigFile := filepath.Join(dir, "haproxy.cfg.template")
+	configFile := filepath.Join(dir, "haproxy.cfg")
+	for _, file := range []string{baseConfigFile, configFile} {
+		if err := ioutil.WriteFile(file, []byte("global\n    maxconn 64000\n"), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	configurer, err := haproxy.NewHaProxyConfigurer(logger, config.HaProxyConfig{}, baseConfigFile, configFile, &monitorFakes.FakeMonitor{}, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return configurer, func() { os.RemoveAll(dir) }
+}
+
+func benchmarkRoutingTable() models.RoutingTable {
+	backends := make(map[models.RoutingKey][]models.BackendServerInfo, benchmarkPorts)
+	for p := 0; p < benchmarkPorts; p++ {
+		infos := make([]models.BackendServerInfo, 0, benchmarkBackendsPerPort)
+		for b := 0; b < benchmarkBackendsPerPort; b++ {
+			infos = append(infos, models.BackendServerInfo{
+				Address:         fmt.Sprintf("10.0.%d.%d", p%256, b),
+				Port:            uint16(60000 + b),
+				ModificationTag: routing_api_models.ModificationTag{Guid: "guid", Index: 1},
+			})
+		}
+		backends[models.RoutingKey{Port: uint16(50000 + p)}] = infos
+	}
+
+	routingTable := models.NewRoutingTable(lager.NewLogger("benchmark"))
+	routingTable.Rebuild(backends)
+	return routingTable
+}
+
+// Renders every port on each call, as a snapshot without generations does.
+func BenchmarkConfigureFullRender(b *testing.B) {
+	configurer, cleanup := benchmarkConfigurer(b, lager.NewLogger("benchmark"))
+	defer cleanup()
+	snapshot := models.RoutingTableSnapshot{Entries: benchmarkRoutingTable().Snapshot().Entries}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := configurer.Configure(snapshot); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Adds and removes a backend of one port between calls, so only that port is
+// rendered. The other ports are still copied into every configuration file.
+func BenchmarkConfigureSingleChange(b *testing.B) {
+	logger := lager.NewLogger("benchmark")
+	sink := &renderedSink{}
+	logger.RegisterSink(sink)
+	configurer, cleanup := benchmarkConfigurer(b, logger)
+	defer cleanup()
+	routingTable := benchmarkRoutingTable()
+	if err := configurer.Configure(routingTable.Snapshot()); err != nil {
+		b.Fatal(err)
+	}
+	key := models.RoutingKey{Port: 50000}
+	backend := models.BackendServerInfo{Address: "10.1.0.1", Port: 61000}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		backend.ModificationTag = routing_api_models.ModificationTag{Guid: "guid", Index: uint32(i + 1)}
+		if i%2 == 0 {
+			routingTable.UpsertBackendServerKey(key, backend)
+		} else {
+			routingTable.DeleteBackendServerKey(key, backend)
+		}
+		if err := configurer.Configure(routingTable.Snapshot()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	if len(sink.rendered) != b.N+1 {
+		b.Fatalf("expected %d configurations, got %d", b.N+1, len(sink.rendered))
+	}
+	for i, rendered := range sink.rendered[1:] {
+		if rendered != 1 {
+			b.Fatalf("call %d rendered %d listen sections, expected 1", i+1, rendered)
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
+	"code.cloudfoundry.org/cf-tcp-router/config"
 	"code.cloudfoundry.org/cf-tcp-router/configurer/haproxy"
 	"code.cloudfoundry.org/cf-tcp-router/configurer/haproxy/fakes"
 	"code.cloudfoundry.org/cf-tcp-router/models"
@@ -43,7 +45,7 @@ var _ = Describe("HaproxyConfigurer", func() {
 
 		Context("when empty base configuration file is passed", func() {
 			It("returns a ErrRouterConfigFileNotFound error", func() {
-				_, err := haproxy.NewHaProxyConfigurer(logger, "", haproxyConfigFile, fakeMonitor, nil)
+				_, err := haproxy.NewHaProxyConfigurer(logger, config.HaProxyConfig{}, "", haproxyConfigFile, fakeMonitor, nil)
 				Expect(err).Should(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring(haproxy.ErrRouterConfigFileNotFound))
 			})
@@ -51,7 +53,7 @@ var _ = Describe("HaproxyConfigurer", func() {
 
 		Context("when empty configuration file is passed", func() {
 			It("returns a ErrRouterConfigFileNotFound error", func() {
-				_, err := haproxy.NewHaProxyConfigurer(logger, haproxyConfigTemplate, "", fakeMonitor, nil)
+				_, err := haproxy.NewHaProxyConfigurer(logger, config.HaProxyConfig{}, haproxyConfigTemplate, "", fakeMonitor, nil)
 				Expect(err).Should(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring(haproxy.ErrRouterConfigFileNotFound))
 			})
@@ -59,7 +61,7 @@ var _ = Describe("HaproxyConfigurer", func() {
 
 		Context("when base configuration file does not exist", func() {
 			It("returns a ErrRouterConfigFileNotFound error", func() {
-				_, err := haproxy.NewHaProxyConfigurer(logger, "file/path/does/not/exist", haproxyConfigFile, fakeMonitor, nil)
+				_, err := haproxy.NewHaProxyConfigurer(logger, config.HaProxyConfig{}, "file/path/does/not/exist", haproxyConfigFile, fakeMonitor, nil)
 				Expect(err).Should(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring(haproxy.ErrRouterConfigFileNotFound))
 			})
@@ -67,7 +69,7 @@ var _ = Describe("HaproxyConfigurer", func() {
 
 		Context("when configuration file does not exist", func() {
 			It("returns a ErrRouterConfigFileNotFound error", func() {
-				_, err := haproxy.NewHaProxyConfigurer(logger, haproxyConfigTemplate, "file/path/does/not/exist", fakeMonitor, nil)
+				_, err := haproxy.NewHaProxyConfigurer(logger, config.HaProxyConfig{}, haproxyConfigTemplate, "file/path/does/not/exist", fakeMonitor, nil)
 				Expect(err).Should(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring(haproxy.ErrRouterConfigFileNotFound))
 			})
@@ -88,7 +90,7 @@ var _ = Describe("HaproxyConfigurer", func() {
 				haproxyConfigTemplateContent, err = ioutil.ReadFile(generatedHaproxyCfgFile)
 				Expect(err).ShouldNot(HaveOccurred())
 
-				haproxyConfigurer, err = haproxy.NewHaProxyConfigurer(logger, haproxyConfigTemplate, generatedHaproxyCfgFile, fakeMonitor, nil)
+				haproxyConfigurer, err = haproxy.NewHaProxyConfigurer(logger, config.HaProxyConfig{}, haproxyConfigTemplate, generatedHaproxyCfgFile, fakeMonitor, nil)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 
@@ -121,7 +123,7 @@ var _ = Describe("HaproxyConfigurer", func() {
 				ok = routingTable.Set(routingKey, routingTableEntry)
 				Expect(ok).To(BeTrue())
 
-				err := haproxyConfigurer.Configure(routingTable)
+				err := haproxyConfigurer.Configure(routingTable.Snapshot())
 				Expect(err).ShouldNot(HaveOccurred())
 
 				validListenCfg := "\nlisten listen_cfg_80\n  mode tcp\n  bind :80\n"
@@ -156,7 +158,7 @@ var _ = Describe("HaproxyConfigurer", func() {
 
 				scriptRunner = &fakes.FakeScriptRunner{}
 
-				haproxyConfigurer, err = haproxy.NewHaProxyConfigurer(logger, haproxyConfigTemplate, generatedHaproxyCfgFile, fakeMonitor, scriptRunner)
+				haproxyConfigurer, err = haproxy.NewHaProxyConfigurer(logger, config.HaProxyConfig{}, haproxyConfigTemplate, generatedHaproxyCfgFile, fakeMonitor, scriptRunner)
 				Expect(err).ShouldNot(HaveOccurred())
 			})
 
@@ -183,7 +185,7 @@ var _ = Describe("HaproxyConfigurer", func() {
 						routinTableKey := models.RoutingKey{Port: 2222}
 						ok := routingTable.Set(routinTableKey, routingTableEntry)
 						Expect(ok).To(BeTrue())
-						err = haproxyConfigurer.Configure(routingTable)
+						err = haproxyConfigurer.Configure(routingTable.Snapshot())
 						Expect(err).ShouldNot(HaveOccurred())
 					})
 
@@ -224,7 +226,7 @@ var _ = Describe("HaproxyConfigurer", func() {
 						ok = routingTable.Set(routinTableKey, routingTableEntry)
 						Expect(ok).To(BeTrue())
 
-						err = haproxyConfigurer.Configure(routingTable)
+						err = haproxyConfigurer.Configure(routingTable.Snapshot())
 						Expect(err).ShouldNot(HaveOccurred())
 					})
 
@@ -265,7 +267,7 @@ listen listen_cfg_3333
 					routinTableKey := models.RoutingKey{Port: 2222}
 					ok := routingTable.Set(routinTableKey, routingTableEntry)
 					Expect(ok).To(BeTrue())
-					err = haproxyConfigurer.Configure(routingTable)
+					err = haproxyConfigurer.Configure(routingTable.Snapshot())
 					Expect(err).ShouldNot(HaveOccurred())
 
 					routingTable = models.NewRoutingTable(logger)
@@ -278,7 +280,7 @@ listen listen_cfg_3333
 					routinTableKey = models.RoutingKey{Port: 3333}
 					ok = routingTable.Set(routinTableKey, routingTableEntry)
 					Expect(ok).To(BeTrue())
-					err = haproxyConfigurer.Configure(routingTable)
+					err = haproxyConfigurer.Configure(routingTable.Snapshot())
 					Expect(err).ShouldNot(HaveOccurred())
 				})
 
@@ -306,6 +308,314 @@ listen listen_cfg_2222
 					Expect(fakeMonitor.StartWatchingCallCount()).To(Equal(2))
 				})
 			})
+
+			Context("when the same routing table changes between calls", func() {
+				var (
+					routingTable              models.RoutingTable
+					haproxyConfigTemplateCopy string
+				)
+
+				BeforeEach(func() {
+					haproxyConfigTemplateCopy = testutil.RandomFileName("fixtures/haproxy_template_", ".cfg")
+					utils.CopyFile(haproxyConfigTemplate, haproxyConfigTemplateCopy)
+					haproxyConfigurer, err = haproxy.NewHaProxyConfigurer(logger, config.HaProxyConfig{}, haproxyConfigTemplateCopy, generatedHaproxyCfgFile, fakeMonitor, scriptRunner)
+					Expect(err).ShouldNot(HaveOccurred())
+
+					routingTable = models.NewRoutingTable(logger)
+					routingTable.UpsertBackendServerKey(models.RoutingKey{Port: 2222}, models.BackendServerInfo{Address: "some-ip-1", Port: 1234})
+					routingTable.UpsertBackendServerKey(models.RoutingKey{Port: 3333}, models.BackendServerInfo{Address: "some-ip-2", Port: 1235})
+					err = haproxyConfigurer.Configure(routingTable.Snapshot())
+					Expect(err).ShouldNot(HaveOccurred())
+
+					routingTable.UpsertBackendServerKey(models.RoutingKey{Port: 2222}, models.BackendServerInfo{Address: "some-ip-3", Port: 2345})
+					routingTable.DeleteBackendServerKey(models.RoutingKey{Port: 3333}, models.BackendServerInfo{Address: "some-ip-2", Port: 1235})
+					err = haproxyConfigurer.Configure(routingTable.Snapshot())
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				AfterEach(func() {
+					err := os.Remove(haproxyConfigTemplateCopy)
+					Expect(err).ShouldNot(HaveOccurred())
+				})
+
+				It("renders the changed ports and drops the removed ones", func() {
+					verifyHaProxyConfigContent(generatedHaproxyCfgFile, "server server_some-ip-1_1234 some-ip-1:1234", true)
+					verifyHaProxyConfigContent(generatedHaproxyCfgFile, "server server_some-ip-3_2345 some-ip-3:2345", true)
+					verifyHaProxyConfigContent(generatedHaproxyCfgFile, "listen listen_cfg_3333", false)
+				})
+
+				Context("and the base configuration file changes", func() {
+					BeforeEach(func() {
+						err = utils.WriteToFile([]byte("global\n  maxconn 1234\n"), haproxyConfigTemplateCopy)
+						Expect(err).ShouldNot(HaveOccurred())
+						err = haproxyConfigurer.Configure(routingTable.Snapshot())
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+
+					It("uses the new base configuration", func() {
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "maxconn 1234", true)
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "listen listen_cfg_2222", true)
+					})
+				})
+			})
+
+			Context("when port options are updated", func() {
+				var portOptions config.PortOptionsConfig
+
+				BeforeEach(func() {
+					portOptions = config.PortOptionsConfig{
+						Defaults: config.PortOptions{Balance: config.BalanceRoundRobin},
+						Ports: map[uint16]config.PortOptions{
+							2222: {Balance: config.BalanceLeastConn},
+						},
+					}
+				})
+
+				Context("before any routing table is configured", func() {
+					It("uses the options for the next configuration", func() {
+						err = haproxyConfigurer.UpdatePortOptions(portOptions)
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(scriptRunner.RunCallCount()).To(Equal(0))
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "balance", false)
+
+						routingTable := models.NewRoutingTable(logger)
+						routingTable.UpsertBackendServerKey(models.RoutingKey{Port: 2222}, models.BackendServerInfo{Address: "some-ip-1", Port: 1234})
+						err = haproxyConfigurer.Configure(routingTable.Snapshot())
+						Expect(err).ShouldNot(HaveOccurred())
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "bind :2222\n  balance leastconn\n", true)
+					})
+				})
+
+				Context("after a routing table is configured", func() {
+					BeforeEach(func() {
+						routingTable := models.NewRoutingTable(logger)
+						routingTable.UpsertBackendServerKey(models.RoutingKey{Port: 2222}, models.BackendServerInfo{Address: "some-ip-1", Port: 1234})
+						routingTable.UpsertBackendServerKey(models.RoutingKey{Port: 3333}, models.BackendServerInfo{Address: "some-ip-2", Port: 1235})
+						err = haproxyConfigurer.Configure(routingTable.Snapshot())
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+
+					It("refuses options that refer to missing files", func() {
+						enabled := true
+						portOptions.Ports[2222] = config.PortOptions{BackendTLS: config.BackendTLSOptions{Enabled: &enabled, CAFile: "fixtures/missing_ca.pem"}}
+						err = haproxyConfigurer.UpdatePortOptions(portOptions)
+						Expect(err).Should(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("port 2222"))
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "balance", false)
+						Expect(scriptRunner.RunCallCount()).To(Equal(1))
+					})
+
+					It("keeps using the last valid certificate after a bad one is rotated in", func() {
+						certDirectory, err := ioutil.TempDir("", "certs")
+						Expect(err).ShouldNot(HaveOccurred())
+						defer os.RemoveAll(certDirectory)
+						copyDirectory, err := ioutil.TempDir("", "router_certs")
+						Expect(err).ShouldNot(HaveOccurred())
+						defer os.RemoveAll(copyDirectory)
+
+						certificate := filepath.Join(certDirectory, "tcp_router.pem")
+						Expect(utils.CopyFile("../../config/fixtures/certs/tcp_router.pem", certificate)).To(Succeed())
+						cfg := config.Config{TLS: config.TLSConfig{CertDirectory: certDirectory, Certificates: map[uint16]string{2222: "tcp_router.pem"}}}
+						copies, _, err := cfg.TLS.LoadCertificates(copyDirectory)
+						Expect(err).ShouldNot(HaveOccurred())
+						routerConfig := cfg
+						routerConfig.TLS = copies
+						err = haproxyConfigurer.UpdatePortOptions(routerConfig.PortOptions(portOptions))
+						Expect(err).ShouldNot(HaveOccurred())
+
+						Expect(utils.CopyFile("../../config/fixtures/certs/corrupt.pem", certificate)).To(Succeed())
+						_, _, err = cfg.TLS.LoadCertificates(copyDirectory)
+						Expect(err).Should(HaveOccurred())
+
+						routingTable := models.NewRoutingTable(logger)
+						routingTable.UpsertBackendServerKey(models.RoutingKey{Port: 2222}, models.BackendServerInfo{Address: "some-ip-3", Port: 1236})
+						err = haproxyConfigurer.Configure(routingTable.Snapshot())
+						Expect(err).ShouldNot(HaveOccurred())
+
+						copy := filepath.Join(copyDirectory, "2222.pem")
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, fmt.Sprintf("bind :2222 ssl crt %s\n", copy), true)
+						valid, err := ioutil.ReadFile("../../config/fixtures/certs/tcp_router.pem")
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(ioutil.ReadFile(copy)).To(Equal(valid))
+					})
+
+					It("adds a resolvers section while a hostname backend is routed", func() {
+						portOptions.Defaults.Nameservers = []string{"10.0.0.2:53"}
+						err = haproxyConfigurer.UpdatePortOptions(portOptions)
+						Expect(err).ShouldNot(HaveOccurred())
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "resolvers tcp_router_dns\n  nameserver dns1 10.0.0.2:53\n", true)
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "some-ip-1:1234 resolvers tcp_router_dns init-addr last,libc,none", true)
+
+						routingTable := models.NewRoutingTable(logger)
+						routingTable.UpsertBackendServerKey(models.RoutingKey{Port: 2222}, models.BackendServerInfo{Address: "10.0.0.1", Port: 1234})
+						err = haproxyConfigurer.Configure(routingTable.Snapshot())
+						Expect(err).ShouldNot(HaveOccurred())
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "resolvers tcp_router_dns", false)
+					})
+
+					It("renders the peers section with the peers of the options", func() {
+						portOptions.Peers = []config.Peer{{Name: "tcp-router-0", Address: "10.0.16.10:10000"}}
+						err = haproxyConfigurer.UpdatePortOptions(portOptions)
+						Expect(err).ShouldNot(HaveOccurred())
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "peers tcp_router_peers\n  peer tcp-router-0 10.0.16.10:10000\n", true)
+
+						portOptions.Peers = nil
+						err = haproxyConfigurer.UpdatePortOptions(portOptions)
+						Expect(err).ShouldNot(HaveOccurred())
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "peers tcp_router_peers", false)
+					})
+
+					It("rewrites the config file with the new options", func() {
+						err = haproxyConfigurer.UpdatePortOptions(portOptions)
+						Expect(err).ShouldNot(HaveOccurred())
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "bind :2222\n  balance leastconn\n", true)
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "bind :3333\n  balance roundrobin\n", true)
+						verifyHaProxyConfigContent(generatedHaproxyCfgFile, "server server_some-ip-2_1235 some-ip-2:1235", true)
+						Expect(scriptRunner.RunCallCount()).To(Equal(2))
+					})
+				})
+			})
+		})
+
+		Context("when the base sections are generated", func() {
+			var (
+				generatedHaproxyCfgFile string
+				baseSections            config.HaProxyConfig
+				routingTable            models.RoutingTable
+			)
+
+			BeforeEach(func() {
+				generatedHaproxyCfgFile = testutil.RandomFileName("fixtures/haproxy_", ".cfg")
+				utils.CopyFile(haproxyConfigTemplate, generatedHaproxyCfgFile)
+				baseSections = config.HaProxyConfig{
+					Enabled: true,
+					Global:  config.HaProxyGlobalConfig{MaxConn: 4096, StatsSocket: "/var/vcap/sys/run/haproxy/stats.sock"},
+				}
+				routingTable = models.NewRoutingTable(logger)
+				routingTable.UpsertBackendServerKey(models.RoutingKey{Port: 2222}, models.BackendServerInfo{Address: "some-ip-1", Port: 1234})
+			})
+
+			AfterEach(func() {
+				os.Remove(generatedHaproxyCfgFile)
+				os.Remove(fmt.Sprintf("%s.bak", generatedHaproxyCfgFile))
+			})
+
+			It("does not need a base configuration file", func() {
+				haproxyConfigurer, err := haproxy.NewHaProxyConfigurer(logger, baseSections, "", generatedHaproxyCfgFile, fakeMonitor, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(haproxyConfigurer.Configure(routingTable.Snapshot())).To(Succeed())
+
+				data, err := ioutil.ReadFile(generatedHaproxyCfgFile)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(string(data)).To(HavePrefix("global\n  maxconn 4096\n  stats socket /var/vcap/sys/run/haproxy/stats.sock mode 600 level admin\n\ndefaults\n\nlisten listen_cfg_2222\n"))
+			})
+
+			It("adds the base configuration file after the generated sections", func() {
+				haproxyConfigurer, err := haproxy.NewHaProxyConfigurer(logger, baseSections, haproxyConfigTemplate, generatedHaproxyCfgFile, fakeMonitor, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(haproxyConfigurer.Configure(routingTable.Snapshot())).To(Succeed())
+
+				template, err := ioutil.ReadFile(haproxyConfigTemplate)
+				Expect(err).ShouldNot(HaveOccurred())
+				data, err := ioutil.ReadFile(generatedHaproxyCfgFile)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(string(data)).To(HavePrefix(haproxy.BaseSectionsToHaProxyConfig(baseSections) + "\n" + string(template)))
+			})
+
+			It("requires a given base configuration file to exist", func() {
+				_, err := haproxy.NewHaProxyConfigurer(logger, baseSections, "file/path/does/not/exist", generatedHaproxyCfgFile, fakeMonitor, nil)
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+
+		Context("when a listen template is configured", func() {
+			var generatedHaproxyCfgFile string
+
+			BeforeEach(func() {
+				generatedHaproxyCfgFile = testutil.RandomFileName("fixtures/haproxy_", ".cfg")
+				utils.CopyFile(haproxyConfigTemplate, generatedHaproxyCfgFile)
+			})
+
+			AfterEach(func() {
+				os.Remove(generatedHaproxyCfgFile)
+				os.Remove(fmt.Sprintf("%s.bak", generatedHaproxyCfgFile))
+			})
+
+			It("renders the listen sections with the template", func() {
+				haproxyConfigurer, err := haproxy.NewHaProxyConfigurer(logger, config.HaProxyConfig{ListenTemplate: "fixtures/listen.tmpl"}, haproxyConfigTemplate, generatedHaproxyCfgFile, fakeMonitor, nil)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(haproxyConfigurer.UpdatePortOptions(config.PortOptionsConfig{
+					Ports: map[uint16]config.PortOptions{2222: {Metadata: map[string]string{"team": "payments"}}},
+				})).To(Succeed())
+
+				routingTable := models.NewRoutingTable(logger)
+				routingTable.UpsertBackendServerKey(models.RoutingKey{Port: 2222}, models.BackendServerInfo{Address: "some-ip-1", Port: 1234})
+				Expect(haproxyConfigurer.Configure(routingTable.Snapshot())).To(Succeed())
+
+				verifyHaProxyConfigContent(generatedHaproxyCfgFile, "listen listen_cfg_2222\n  mode tcp\n  bind :2222\n  # team payments\n  server server_some-ip-1_1234 some-ip-1:1234 init-addr last,libc,none\n", true)
+			})
+
+			It("fails to start with a template that does not render the routes", func() {
+				_, err := haproxy.NewHaProxyConfigurer(logger, config.HaProxyConfig{ListenTemplate: "fixtures/listen_without_servers.tmpl"}, haproxyConfigTemplate, generatedHaproxyCfgFile, fakeMonitor, nil)
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("CheckStatsSocket", func() {
+		const statsSocket = "/var/vcap/sys/run/haproxy/stats.sock"
+
+		var baseConfigFile string
+
+		writeBaseConfig := func(content string) {
+			baseConfigFile = testutil.RandomFileName("fixtures/base_", ".cfg")
+			Expect(ioutil.WriteFile(baseConfigFile, []byte(content), 0644)).To(Succeed())
+		}
+
+		AfterEach(func() {
+			if baseConfigFile != "" {
+				os.Remove(baseConfigFile)
+				baseConfigFile = ""
+			}
+		})
+
+		Context("when the base sections are generated", func() {
+			var baseSections config.HaProxyConfig
+
+			BeforeEach(func() {
+				baseSections = config.HaProxyConfig{Enabled: true, Global: config.HaProxyGlobalConfig{StatsSocket: statsSocket}}
+			})
+
+			It("accepts the stats socket the router reads", func() {
+				Expect(haproxy.CheckStatsSocket(baseSections, "", statsSocket)).To(Succeed())
+			})
+
+			It("rejects a different stats socket", func() {
+				err := haproxy.CheckStatsSocket(baseSections, "", "/other/haproxy.sock")
+				Expect(err).Should(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("does not match"))
+			})
+
+			It("rejects a base configuration file that declares a stats socket", func() {
+				writeBaseConfig("global\n  stats socket " + statsSocket + " mode 600 level admin\n")
+				err := haproxy.CheckStatsSocket(baseSections, baseConfigFile, statsSocket)
+				Expect(err).Should(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("generated"))
+			})
+		})
+
+		Context("when the base configuration file provides the base sections", func() {
+			It("accepts a file that declares the stats socket", func() {
+				writeBaseConfig("global\n  maxconn 4096\n  stats socket " + statsSocket + " mode 600 level admin\n\ndefaults\n  log global\n")
+				Expect(haproxy.CheckStatsSocket(config.HaProxyConfig{}, baseConfigFile, statsSocket)).To(Succeed())
+			})
+
+			It("rejects a file without the stats socket", func() {
+				writeBaseConfig("global\n  maxconn 4096\n\nlisten stats\n  stats socket " + statsSocket + "\n")
+				err := haproxy.CheckStatsSocket(config.HaProxyConfig{}, baseConfigFile, statsSocket)
+				Expect(err).Should(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("does not declare"))
+			})
 		})
 	})
 })
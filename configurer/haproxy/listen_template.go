@@ -0,0 +1,173 @@
+package haproxy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"text/template"
+	"time"
+
+	"code.cloudfoundry.org/cf-tcp-router/config"
+	"code.cloudfoundry.org/cf-tcp-router/models"
+	"code.cloudfoundry.org/lager"
+)
+
+// DefaultListenTemplateText renders the listen section of a port when no
+// listen template is configured. It is a starting point for custom templates.
+const DefaultListenTemplateText = `listen {{.Name}}
+  mode tcp
+  bind {{.Bind}}
+{{range .Rules}}  {{.}}
+{{end}}{{range .Servers}}  server {{.Name}} {{.Endpoint}}{{.Params}}
+{{end}}`
+
+// ListenData is what a listen template renders the listen section of a port
+// from. Bind, Rules and the Params of the servers hold the configuration the
+// built-in template renders, so that templates only rewrite what they change.
+//
+// The router reads the routes of a port back from the configuration file, so
+// the section must stay named Name and keep a server line per route backend:
+// "server <name> <endpoint>", followed by "weight 0" for draining backends.
+type ListenData struct {
+	Port uint16
+	Name string
+	// Bind is the address of the bind line followed by its parameters, e.g.
+	// ":443 ssl crt /path/to/cert.pem accept-proxy".
+	Bind string
+	// Rules are the lines between the bind line and the servers, without
+	// indentation: options, source ACLs, rate limits and, while the port is
+	// held down without a fallback or backup backend, its reject rule.
+	Rules []string
+	// Servers are the route backends ordered by address and port, or the
+	// fallback backend of a held down port, followed by the backup backends.
+	Servers []ServerData
+	// Options are the options of the port, layered over the defaults.
+	Options config.PortOptions
+	// Metadata is the operator metadata of the port options.
+	Metadata map[string]string
+	// HeldDown is set while the port is held open without route backends.
+	HeldDown bool
+}
+
+type ServerData struct {
+	Name string
+	// Address is the host name or IP of the server, and Endpoint its
+	// "address:port" with IPv6 addresses in brackets.
+	Address  string
+	Port     uint16
+	Endpoint string
+	Weight   int
+	Draining bool
+	Backup   bool
+	Fallback bool
+	// Params are the server parameters that follow the endpoint, with a
+	// leading space, e.g. " weight 10 check inter 2000ms".
+	Params string
+}
+
+func (s ServerData) line() string {
+	return fmt.Sprintf("server %s %s%s\n", s.Name, s.Endpoint, s.Params)
+}
+
+// ListenTemplate renders the listen sections of the configuration. Besides
+// the text/template builtins, templates can call "ms", which formats a
+// duration as HAProxy milliseconds, e.g. {{ms .Options.ClientTimeout}}.
+type ListenTemplate struct {
+	template *template.Template
+}
+
+var defaultListenTemplate = ListenTemplate{
+	template: template.Must(newListenTemplate("default").Parse(DefaultListenTemplateText)),
+}
+
+func DefaultListenTemplate() ListenTemplate {
+	return defaultListenTemplate
+}
+
+// LoadListenTemplate parses the listen template file and checks that the
+// routes of a sample routing table can be read back from what it renders.
+func LoadListenTemplate(logger lager.Logger, path string) (ListenTemplate, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ListenTemplate{}, err
+	}
+	tmpl, err := newListenTemplate(filepath.Base(path)).Parse(string(text))
+	if err != nil {
+		return ListenTemplate{}, err
+	}
+	listenTemplate := ListenTemplate{template: tmpl}
+	if err := listenTemplate.validate(logger); err != nil {
+		return ListenTemplate{}, fmt.Errorf("listen template %s: %s", path, err.Error())
+	}
+	return listenTemplate, nil
+}
+
+func newListenTemplate(name string) *template.Template {
+	return template.New(name).Funcs(template.FuncMap{
+		"ms": func(d time.Duration) int64 { return int64(d / time.Millisecond) },
+	})
+}
+
+func (t ListenTemplate) Render(routingKey models.RoutingKey, routingTableEntry models.RoutingTableEntry, options config.PortOptions) (string, error) {
+	data, err := listenData(routingKey, routingTableEntry, options)
+	if err != nil {
+		return "", err
+	}
+	var buff bytes.Buffer
+	if err := t.template.Execute(&buff, data); err != nil {
+		return "", err
+	}
+	return buff.String(), nil
+}
+
+// The sample routing table has a weighted, an IPv6 and a draining backend on
+// one port, and a held down port, with backup backends and metadata set.
+func (t ListenTemplate) validate(logger lager.Logger) error {
+	routingKey := models.RoutingKey{Port: 1024}
+	entry := models.NewRoutingTableEntry([]models.BackendServerInfo{
+		{Address: "10.0.0.1", Port: 61000, Weight: 10},
+		{Address: "fd00::1", Port: 61000},
+	})
+	drainingKey := models.BackendServerKey{Address: "10.0.0.2", Port: 61000}
+	entry.Backends[drainingKey] = models.BackendServerDetails{DrainingSince: time.Now()}
+
+	heldKey := models.RoutingKey{Port: 1025}
+	held := models.RoutingTableEntry{Backends: map[models.BackendServerKey]models.BackendServerDetails{}, EmptySince: time.Now()}
+
+	enabled := true
+	options := config.PortOptions{
+		Balance:        config.BalanceRoundRobin,
+		ClientTimeout:  30 * time.Second,
+		HealthCheck:    config.HealthCheckOptions{Enabled: &enabled},
+		BackupBackends: []string{"10.0.0.9:8080"},
+		Metadata:       map[string]string{"team": "sample"},
+	}
+
+	var buff bytes.Buffer
+	for key, e := range map[models.RoutingKey]models.RoutingTableEntry{routingKey: entry, heldKey: held} {
+		section, err := t.Render(key, e, options)
+		if err != nil {
+			return err
+		}
+		buff.WriteString(section)
+	}
+
+	routingTable, err := ParseRoutingTable(logger, &buff)
+	if err != nil {
+		return err
+	}
+	expected := map[models.BackendServerKey]bool{}
+	for key := range entry.Backends {
+		expected[key] = key == drainingKey
+	}
+	parsed := map[models.BackendServerKey]bool{}
+	for key, details := range routingTable.Get(routingKey).Backends {
+		parsed[key] = details.Draining()
+	}
+	if !reflect.DeepEqual(parsed, expected) || !routingTable.Get(heldKey).HeldDown() || routingTable.Size() != 2 {
+		return fmt.Errorf("the routes of the sample routing table cannot be read back from the rendered configuration")
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package haproxy_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/cf-tcp-router/config"
+	"code.cloudfoundry.org/cf-tcp-router/configurer/haproxy"
+	"code.cloudfoundry.org/cf-tcp-router/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ListenTemplate", func() {
+	var (
+		routingKey models.RoutingKey
+		entry      models.RoutingTableEntry
+		options    config.PortOptions
+	)
+
+	BeforeEach(func() {
+		routingKey = models.RoutingKey{Port: 8880}
+		entry = models.NewRoutingTableEntry([]models.BackendServerInfo{
+			{Address: "some-ip-2", Port: 1234},
+			{Address: "some-ip-1", Port: 1234, Weight: 5},
+		})
+		options = config.PortOptions{
+			ClientTimeout: 30 * time.Second,
+			Metadata:      map[string]string{"team": "payments"},
+		}
+	})
+
+	Describe("DefaultListenTemplate", func() {
+		It("renders the servers ordered by address", func() {
+			str, err := haproxy.DefaultListenTemplate().Render(routingKey, entry, options)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n  timeout client 30000ms\n  server server_some-ip-1_1234 some-ip-1:1234 weight 5 init-addr last,libc,none\n  server server_some-ip-2_1234 some-ip-2:1234 init-addr last,libc,none\n"))
+		})
+	})
+
+	Describe("LoadListenTemplate", func() {
+		It("renders listen sections with the template", func() {
+			listenTemplate, err := haproxy.LoadListenTemplate(logger, "fixtures/listen.tmpl")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			str, err := listenTemplate.Render(routingKey, entry, options)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(str).Should(Equal("listen listen_cfg_8880\n  mode tcp\n  bind :8880\n  # team payments\n  timeout client 30000ms\n  server server_some-ip-1_1234 some-ip-1:1234 weight 5 init-addr last,libc,none\n  server server_some-ip-2_1234 some-ip-2:1234 init-addr last,libc,none\n\n"))
+		})
+
+		It("returns the validation errors of the listen configuration", func() {
+			listenTemplate, err := haproxy.LoadListenTemplate(logger, "fixtures/listen.tmpl")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = listenTemplate.Render(models.RoutingKey{}, entry, options)
+			Expect(err).Should(HaveOccurred())
+			Expect(err).Should(Equal(haproxy.ErrInvalidField{Field: "listen_configuration.port"}))
+		})
+
+		Context("when the template does not render the route backends", func() {
+			It("returns an error", func() {
+				_, err := haproxy.LoadListenTemplate(logger, "fixtures/listen_without_servers.tmpl")
+				Expect(err).Should(HaveOccurred())
+				Expect(err.Error()).Should(ContainSubstring("cannot be read back"))
+			})
+		})
+
+		Context("when the template is malformed", func() {
+			It("returns an error", func() {
+				_, err := haproxy.LoadListenTemplate(logger, "fixtures/malformed_listen.tmpl")
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+
+		Context("when the template file does not exist", func() {
+			It("returns an error", func() {
+				_, err := haproxy.LoadListenTemplate(logger, "fixtures/does_not_exist.tmpl")
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+	})
+})
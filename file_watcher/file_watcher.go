@@ -0,0 +1,98 @@
+package file_watcher
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+// FileWatcher polls a file, or the files of a directory, and calls onChange
+// whenever their modification time or size changes.
+type FileWatcher struct {
+	clock         clock.Clock
+	pollInterval  time.Duration
+	path          string
+	onChange      func() error
+	logger        lager.Logger
+	lastSignature string
+}
+
+func New(
+	clock clock.Clock,
+	pollInterval time.Duration,
+	path string,
+	onChange func() error,
+	logger lager.Logger,
+) *FileWatcher {
+	return &FileWatcher{
+		clock:        clock,
+		pollInterval: pollInterval,
+		path:         path,
+		onChange:     onChange,
+		logger:       logger.Session("file-watcher", lager.Data{"path": path}),
+	}
+}
+
+func (w *FileWatcher) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	w.lastSignature = w.signature()
+	close(ready)
+	w.logger.Info("started")
+
+	ticker := w.clock.NewTicker(w.pollInterval)
+
+	for {
+		select {
+		case <-ticker.C():
+			w.poll()
+		case <-signals:
+			w.logger.Info("stopping")
+			ticker.Stop()
+			return nil
+		}
+	}
+}
+
+func (w *FileWatcher) poll() {
+	signature := w.signature()
+	if signature == w.lastSignature {
+		return
+	}
+
+	w.logger.Info("file-changed")
+	err := w.onChange()
+	if err != nil {
+		w.logger.Error("failed-to-apply-change", err)
+		return
+	}
+	w.lastSignature = signature
+}
+
+// A missing or unreadable path has an empty signature, so that it is reported
+// as a change once it appears.
+func (w *FileWatcher) signature() string {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return ""
+	}
+	if !info.IsDir() {
+		return fileSignature(info)
+	}
+
+	infos, err := ioutil.ReadDir(w.path)
+	if err != nil {
+		return ""
+	}
+	signature := fileSignature(info)
+	for _, info := range infos {
+		signature += fileSignature(info)
+	}
+	return signature
+}
+
+func fileSignature(info os.FileInfo) string {
+	return fmt.Sprintf("%s:%d:%d;", info.Name(), info.ModTime().UnixNano(), info.Size())
+}
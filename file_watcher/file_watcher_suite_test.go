@@ -0,0 +1,13 @@
+package file_watcher_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFileWatcher(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "FileWatcher Suite")
+}
@@ -0,0 +1,116 @@
+package file_watcher_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/cf-tcp-router/file_watcher"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileWatcher", func() {
+	const pollInterval = 10 * time.Second
+
+	var (
+		dir     string
+		path    string
+		clock   *fakeclock.FakeClock
+		changes int32
+		failing int32
+		process ifrit.Process
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "file-watcher")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "watched.yml")
+		Expect(ioutil.WriteFile(path, []byte("a"), 0644)).To(Succeed())
+
+		clock = fakeclock.NewFakeClock(time.Now())
+		atomic.StoreInt32(&changes, 0)
+		atomic.StoreInt32(&failing, 0)
+	})
+
+	JustBeforeEach(func() {
+		onChange := func() error {
+			atomic.AddInt32(&changes, 1)
+			if atomic.LoadInt32(&failing) == 1 {
+				return errors.New("boom")
+			}
+			return nil
+		}
+		watcher := file_watcher.New(clock, pollInterval, path, onChange, lagertest.NewTestLogger("test"))
+		process = ifrit.Invoke(watcher)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		os.RemoveAll(dir)
+	})
+
+	changeCount := func() int32 {
+		return atomic.LoadInt32(&changes)
+	}
+
+	Context("when the file does not change", func() {
+		It("does not call the callback", func() {
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			clock.Increment(pollInterval)
+			Consistently(changeCount).Should(BeZero())
+		})
+	})
+
+	Context("when the file changes", func() {
+		It("calls the callback once per change", func() {
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			Expect(ioutil.WriteFile(path, []byte("ab"), 0644)).To(Succeed())
+
+			clock.Increment(pollInterval)
+			Eventually(changeCount).Should(Equal(int32(1)))
+
+			clock.Increment(pollInterval)
+			Consistently(changeCount).Should(Equal(int32(1)))
+		})
+	})
+
+	Context("when the callback fails", func() {
+		BeforeEach(func() {
+			atomic.StoreInt32(&failing, 1)
+		})
+
+		It("retries on the next poll", func() {
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			Expect(ioutil.WriteFile(path, []byte("ab"), 0644)).To(Succeed())
+
+			clock.Increment(pollInterval)
+			Eventually(changeCount).Should(Equal(int32(1)))
+			clock.Increment(pollInterval)
+			Eventually(changeCount).Should(Equal(int32(2)))
+		})
+	})
+
+	Context("when a directory is watched", func() {
+		BeforeEach(func() {
+			path = dir
+		})
+
+		It("calls the callback when a file is added", func() {
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			Expect(ioutil.WriteFile(filepath.Join(dir, "other.yml"), []byte("b"), 0644)).To(Succeed())
+
+			clock.Increment(pollInterval)
+			Eventually(changeCount).Should(Equal(int32(1)))
+		})
+	})
+})
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
@@ -13,12 +14,14 @@ import (
 	"code.cloudfoundry.org/cf-tcp-router/config"
 	"code.cloudfoundry.org/cf-tcp-router/configurer"
 	"code.cloudfoundry.org/cf-tcp-router/configurer/haproxy"
+	"code.cloudfoundry.org/cf-tcp-router/file_watcher"
 	"code.cloudfoundry.org/cf-tcp-router/metrics_reporter"
 	"code.cloudfoundry.org/cf-tcp-router/metrics_reporter/haproxy_client"
 	"code.cloudfoundry.org/cf-tcp-router/models"
 	"code.cloudfoundry.org/cf-tcp-router/monitor"
 	"code.cloudfoundry.org/cf-tcp-router/routing_table"
 	"code.cloudfoundry.org/cf-tcp-router/syncer"
+	"code.cloudfoundry.org/cf-tcp-router/utils"
 	"code.cloudfoundry.org/cf-tcp-router/watcher"
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/debugserver"
@@ -47,7 +50,7 @@ var tcpLoadBalancer = flag.String(
 var tcpLoadBalancerBaseCfg = flag.String(
 	"tcpLoadBalancerBaseConfig",
 	"",
-	"The tcp load balancer base configuration file name. This contains the basic header information.",
+	"The tcp load balancer base configuration file name. This contains the basic header information, and is optional when the router config generates it.",
 )
 
 var tcpLoadBalancerCfg = flag.String(
@@ -110,6 +113,12 @@ var statsCollectionInterval = flag.Duration(
 	"The interval between collection of stats from tcp load balancer.",
 )
 
+var configFilePollInterval = flag.Duration(
+	"configFilePollInterval",
+	10*time.Second,
+	"The interval at which router checks auxiliary configuration files, such as the port options file, for changes",
+)
+
 var dropsondePort = flag.Int(
 	"dropsondePort",
 	3457,
@@ -155,17 +164,48 @@ func main() {
 
 	monitor := monitor.New(cfg.HaProxyPidFile, logger)
 
-	routingTable := models.NewRoutingTable(logger)
+	if cfg.HaProxy.Global.StatsSocket == "" {
+		cfg.HaProxy.Global.StatsSocket = *tcpLoadBalancerStatsUnixSocket
+	}
+	err = haproxy.CheckStatsSocket(cfg.HaProxy, *tcpLoadBalancerBaseCfg, *tcpLoadBalancerStatsUnixSocket)
+	if err != nil {
+		if cfg.HaProxy.Enabled {
+			logger.Error("conflicting-haproxy-config", err)
+			os.Exit(1)
+		}
+		// metrics and draining cannot read stats until the base config is fixed
+		logger.Error("missing-haproxy-stats-socket", err)
+	}
+
+	routingTable := initialRoutingTable(logger).WithDrainTimeout(cfg.DrainTimeout).WithEmptyPortHoldDown(cfg.EmptyPortHoldDown)
+	// Draining backends and held ports read back from the configuration file
+	// are only removed by their timers, which do not run while disabled.
+	if cfg.DrainTimeout == 0 {
+		routingTable.RemoveDrainedBackends(func(models.RoutingKey, models.BackendServerKey, models.BackendServerDetails) bool { return true })
+	}
+	if cfg.EmptyPortHoldDown == 0 {
+		routingTable.ReleaseHeldPorts()
+	}
 	reloaderRunner := haproxy.CreateCommandRunner(*haproxyReloader, logger)
 	configurer := configurer.NewConfigurer(
 		logger,
 		*tcpLoadBalancer,
+		cfg.HaProxy,
 		*tcpLoadBalancerBaseCfg,
 		*tcpLoadBalancerCfg,
 		monitor,
 		reloaderRunner,
 	)
 
+	haproxyClient := haproxy_client.NewClient(logger, *tcpLoadBalancerStatsUnixSocket, statsConnectionTimeout)
+	metricsEmitter := metrics_reporter.NewMetricsEmitter()
+	metricsReporter := metrics_reporter.NewMetricsReporter(clock, haproxyClient, metricsEmitter, *statsCollectionInterval)
+
+	err = loadPortOptions(logger, cfg, configurer, metricsReporter)
+	if err != nil {
+		os.Exit(1)
+	}
+
 	// Reap child processes to prevent zombies when running in a container (BPM)
 	go func() {
 		signalChannel := make(chan os.Signal)
@@ -209,14 +249,17 @@ func main() {
 
 	go startRoutePruner(ticker, updater)
 
+	if cfg.DrainTimeout > 0 {
+		go startDrainedBackendRemover(clock.NewTicker(*statsCollectionInterval), updater, haproxyClient)
+	}
+	if cfg.EmptyPortHoldDown > 0 {
+		go startHeldPortReleaser(clock.NewTicker(*staleRouteCheckInterval), updater)
+	}
+
 	syncChannel := make(chan struct{})
 	syncRunner := syncer.New(clock, *syncInterval, syncChannel, logger)
 	watcher := watcher.New(routingAPIClient, updater, uaaClient, *subscriptionRetryInterval, syncChannel, logger)
 
-	haproxyClient := haproxy_client.NewClient(logger, *tcpLoadBalancerStatsUnixSocket, statsConnectionTimeout)
-	metricsEmitter := metrics_reporter.NewMetricsEmitter()
-	metricsReporter := metrics_reporter.NewMetricsReporter(clock, haproxyClient, metricsEmitter, *statsCollectionInterval)
-
 	members := grouper.Members{
 		{"watcher", watcher},
 		{"syncer", syncRunner},
@@ -224,6 +267,37 @@ func main() {
 		{"monitor", monitor},
 	}
 
+	reloadPortOptions := func() error {
+		return loadPortOptions(logger, cfg, configurer, metricsReporter)
+	}
+	if cfg.PortOptionsFile != "" {
+		members = append(members, grouper.Member{
+			Name:   "portOptionsWatcher",
+			Runner: file_watcher.New(clock, *configFilePollInterval, cfg.PortOptionsFile, reloadPortOptions, logger),
+		})
+	}
+
+	if cfg.SourceACLFile != "" {
+		members = append(members, grouper.Member{
+			Name:   "sourceACLWatcher",
+			Runner: file_watcher.New(clock, *configFilePollInterval, cfg.SourceACLFile, reloadPortOptions, logger),
+		})
+	}
+
+	if cfg.TLS.CertDirectory != "" {
+		members = append(members, grouper.Member{
+			Name:   "certificateWatcher",
+			Runner: file_watcher.New(clock, *configFilePollInterval, cfg.TLS.CertDirectory, reloadPortOptions, logger),
+		})
+	}
+
+	if resolvConf := cfg.Resolvers.ResolvConfPath(); resolvConf != "" {
+		members = append(members, grouper.Member{
+			Name:   "resolvConfWatcher",
+			Runner: file_watcher.New(clock, *configFilePollInterval, resolvConf, reloadPortOptions, logger),
+		})
+	}
+
 	if dbgAddr := debugserver.DebugAddress(flag.CommandLine); dbgAddr != "" {
 		members = append(grouper.Members{
 			{"debug-server", debugserver.Runner(dbgAddr, reconfigurableSink)},
@@ -254,6 +328,96 @@ func startRoutePruner(ticker clock.Ticker, updater routing_table.Updater) {
 	}
 }
 
+func startDrainedBackendRemover(ticker clock.Ticker, updater routing_table.Updater, haproxyClient haproxy_client.HaproxyClient) {
+	for {
+		select {
+		case <-ticker.C():
+			updater.RemoveDrainedBackends(haproxyClient.GetStats())
+		}
+	}
+}
+
+func startHeldPortReleaser(ticker clock.Ticker, updater routing_table.Updater) {
+	for {
+		select {
+		case <-ticker.C():
+			updater.ReleaseHeldPorts()
+		}
+	}
+}
+
+// initialRoutingTable rebuilds the routing table from the last generated load
+// balancer configuration so that a restart does not drop existing routes before
+// the first sync completes.
+func initialRoutingTable(logger lager.Logger) models.RoutingTable {
+	if *tcpLoadBalancer != configurer.HaProxyConfigurer || !utils.FileExists(*tcpLoadBalancerCfg) {
+		return models.NewRoutingTable(logger)
+	}
+
+	routingTable, err := haproxy.RoutingTableFromConfigFile(logger, *tcpLoadBalancerCfg)
+	if err != nil {
+		logger.Error("failed-to-load-routing-table-from-config", err, lager.Data{"config-file": *tcpLoadBalancerCfg})
+		return models.NewRoutingTable(logger)
+	}
+	logger.Info("loaded-routing-table-from-config", lager.Data{"size": routingTable.Size()})
+	return routingTable
+}
+
+// loadPortOptions applies the port options and source ACL files, if any,
+// combined with the port defaults, TLS certificates and nameservers of the
+// router config. Certificates are validated first, so that a bad certificate
+// never reaches the load balancer, and the configuration refers to copies of
+// them next to the generated configuration file, so that a bad certificate
+// rotated in later is not picked up by the next reload either. The metrics
+// reporter emits the expiry of the certificates once they are applied.
+func loadPortOptions(logger lager.Logger, cfg *config.Config, configurer configurer.RouterConfigurer, metricsReporter *metrics_reporter.MetricsReporter) error {
+	certDirectory := filepath.Join(filepath.Dir(*tcpLoadBalancerCfg), "certs")
+	tlsConfig, certificates, err := cfg.TLS.LoadCertificates(certDirectory)
+	if err != nil {
+		logger.Error("failed-to-load-certificates", err, lager.Data{"cert-directory": cfg.TLS.CertDirectory})
+		return err
+	}
+
+	nameservers, err := cfg.Resolvers.LoadNameservers()
+	if err != nil {
+		logger.Error("failed-to-load-nameservers", err, lager.Data{"resolv-conf": cfg.Resolvers.ResolvConfPath()})
+		return err
+	}
+
+	path := cfg.PortOptionsFile
+	portOptions := config.PortOptionsConfig{}
+	if path != "" {
+		portOptions, err = config.LoadPortOptions(path)
+		if err != nil {
+			logger.Error("failed-to-load-port-options", err, lager.Data{"port-options-file": path})
+			return err
+		}
+	}
+
+	sourceACLs := config.SourceACLConfig{}
+	if cfg.SourceACLFile != "" {
+		sourceACLs, err = config.LoadSourceACLs(cfg.SourceACLFile)
+		if err != nil {
+			logger.Error("failed-to-load-source-acls", err, lager.Data{"source-acl-file": cfg.SourceACLFile})
+			return err
+		}
+	}
+
+	routerConfig := *cfg
+	routerConfig.TLS = tlsConfig
+	options := routerConfig.PortOptions(portOptions).WithSourceACLs(sourceACLs)
+	options.Defaults.Nameservers = nameservers
+	options.Defaults.HoldValid = cfg.Resolvers.HoldValid
+	err = configurer.UpdatePortOptions(options)
+	if err != nil {
+		logger.Error("failed-to-apply-port-options", err, lager.Data{"port-options-file": path})
+		return err
+	}
+	metricsReporter.UpdateCertificates(certificates)
+	logger.Info("loaded-port-options", lager.Data{"port-options-file": path, "ports": len(portOptions.Ports), "certificates": len(certificates), "source-acls": len(sourceACLs.Ports)})
+	return nil
+}
+
 func newUaaClient(logger lager.Logger, c *config.Config, klok clock.Clock) uaaclient.Client {
 	if c.RoutingAPI.AuthDisabled {
 		logger.Debug("creating-noop-uaa-client")
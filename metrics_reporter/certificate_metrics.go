@@ -0,0 +1,17 @@
+package metrics_reporter
+
+import (
+	"strconv"
+
+	"code.cloudfoundry.org/cf-tcp-router/config"
+)
+
+var tlsCertificateExpiry = ProxyValue("TLSCertificateExpiry")
+
+// Reports, for every port that terminates TLS, when its certificate expires in
+// seconds since the epoch.
+func emitCertificateExpiries(certificates map[uint16]config.Certificate) {
+	for port, certificate := range certificates {
+		tlsCertificateExpiry.Send(strconv.Itoa(int(port)), uint64(certificate.NotAfter.Unix()))
+	}
+}
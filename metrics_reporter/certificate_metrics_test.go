@@ -0,0 +1,64 @@
+package metrics_reporter_test
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/cf-tcp-router/config"
+	"code.cloudfoundry.org/cf-tcp-router/metrics_reporter"
+	emitter_fakes "code.cloudfoundry.org/cf-tcp-router/metrics_reporter/fakes"
+	haproxy_fakes "code.cloudfoundry.org/cf-tcp-router/metrics_reporter/haproxy_client/fakes"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/cloudfoundry/dropsonde/metric_sender/fake"
+	"github.com/cloudfoundry/dropsonde/metrics"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CertificateMetrics", func() {
+	var (
+		sender          *fake.FakeMetricSender
+		clock           *fakeclock.FakeClock
+		metricsReporter *metrics_reporter.MetricsReporter
+		process         ifrit.Process
+	)
+
+	BeforeEach(func() {
+		sender = fake.NewFakeMetricSender()
+		metrics.Initialize(sender, nil)
+		clock = fakeclock.NewFakeClock(time.Now())
+		metricsReporter = metrics_reporter.NewMetricsReporter(clock, &haproxy_fakes.FakeHaproxyClient{}, &emitter_fakes.FakeMetricsEmitter{}, time.Second)
+		process = ifrit.Invoke(metricsReporter)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("emits the expiry of the last loaded certificates on every interval", func() {
+		notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		metricsReporter.UpdateCertificates(map[uint16]config.Certificate{
+			443: {Path: "/path/to/cert.pem", NotAfter: notAfter},
+		})
+		Consistently(func() fake.Metric {
+			return sender.GetValue("443.TLSCertificateExpiry")
+		}).Should(Equal(fake.Metric{}))
+
+		clock.Increment(time.Second + 100*time.Millisecond)
+		Eventually(func() fake.Metric {
+			return sender.GetValue("443.TLSCertificateExpiry")
+		}).Should(Equal(fake.Metric{Value: float64(notAfter.Unix()), Unit: "Metric"}))
+
+		rotated := notAfter.AddDate(1, 0, 0)
+		metricsReporter.UpdateCertificates(map[uint16]config.Certificate{
+			443: {Path: "/path/to/cert.pem", NotAfter: rotated},
+		})
+		clock.Increment(time.Second + 100*time.Millisecond)
+		Eventually(func() fake.Metric {
+			return sender.GetValue("443.TLSCertificateExpiry")
+		}).Should(Equal(fake.Metric{Value: float64(rotated.Unix()), Unit: "Metric"}))
+	})
+})
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/lager"
@@ -26,14 +27,29 @@ type HaproxyStats []HaproxyStat
 
 type HaproxyStat struct {
 	ProxyName            string `csv:"pxname"`
+	ServerName           string `csv:"svname"`
+	Status               string `csv:"status"`
+	CheckFailures        uint64 `csv:"chkfail"`
 	CurrentQueued        uint64 `csv:"qcur"`
 	CurrentSessions      uint64 `csv:"scur"`
 	ErrorConnecting      uint64 `csv:"econ"`
 	AverageQueueTimeMs   uint64 `csv:"qtime"`
 	AverageConnectTimeMs uint64 `csv:"ctime"`
 	AverageSessionTimeMs uint64 `csv:"ttime"`
+	FailedHandshakes     uint64 `csv:"ssl_failed_handshake"`
+	DeniedConnections    uint64 `csv:"dcon"`
+	DeniedSessions       uint64 `csv:"dses"`
+	DeniedRequests       uint64 `csv:"dreq"`
 }
 
+// Only reported by some HAProxy versions, so these are looked up by name in
+// the header instead of by position.
+const (
+	failedHandshakesColumn  = "ssl_failed_handshake"
+	deniedConnectionsColumn = "dcon"
+	deniedSessionsColumn    = "dses"
+)
+
 func NewClient(logger lager.Logger, haproxyUnixSocket string, timeout time.Duration) *HaproxyStatsClient {
 	return &HaproxyStatsClient{
 		haproxyUnixSocket: haproxyUnixSocket,
@@ -99,12 +115,20 @@ func readCsv(logger lager.Logger, buffer []byte) HaproxyStats {
 		return stats
 	}
 
+	failedHandshakesIndex, deniedConnectionsIndex, deniedSessionsIndex := -1, -1, -1
 	for i, line := range lines {
 		if i == 0 {
 			// skip header line
+			failedHandshakesIndex = columnIndex(line, failedHandshakesColumn)
+			deniedConnectionsIndex = columnIndex(line, deniedConnectionsColumn)
+			deniedSessionsIndex = columnIndex(line, deniedSessionsColumn)
 			continue
 		}
-		stats = append(stats, csvToHaproxyStat(line))
+		stat := csvToHaproxyStat(line)
+		stat.FailedHandshakes = optionalColumn(line, failedHandshakesIndex)
+		stat.DeniedConnections = optionalColumn(line, deniedConnectionsIndex)
+		stat.DeniedSessions = optionalColumn(line, deniedSessionsIndex)
+		stats = append(stats, stat)
 	}
 	return stats
 }
@@ -112,8 +136,12 @@ func readCsv(logger lager.Logger, buffer []byte) HaproxyStats {
 func csvToHaproxyStat(row []string) HaproxyStat {
 	return HaproxyStat{
 		ProxyName:            row[0],
+		ServerName:           row[1],
+		Status:               row[17],
+		CheckFailures:        convertToInt(row[21]),
 		CurrentQueued:        convertToInt(row[2]),
 		CurrentSessions:      convertToInt(row[4]),
+		DeniedRequests:       convertToInt(row[10]),
 		ErrorConnecting:      convertToInt(row[13]),
 		AverageQueueTimeMs:   convertToInt(row[58]),
 		AverageConnectTimeMs: convertToInt(row[59]),
@@ -121,6 +149,22 @@ func csvToHaproxyStat(row []string) HaproxyStat {
 	}
 }
 
+func columnIndex(header []string, column string) int {
+	for i, name := range header {
+		if strings.TrimPrefix(name, "# ") == column {
+			return i
+		}
+	}
+	return -1
+}
+
+func optionalColumn(row []string, index int) uint64 {
+	if index < 0 || index >= len(row) {
+		return 0
+	}
+	return convertToInt(row[index])
+}
+
 func convertToInt(s string) uint64 {
 	i, _ := strconv.ParseUint(s, 10, 64)
 	return i
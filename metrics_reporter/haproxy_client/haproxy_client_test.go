@@ -86,6 +86,8 @@ var _ = Describe("HaproxyClient", func() {
 
 				r0 := haproxy_client.HaproxyStat{
 					ProxyName:            "stats",
+					ServerName:           "FRONTEND",
+					Status:               "OPEN",
 					CurrentQueued:        100,
 					CurrentSessions:      101,
 					ErrorConnecting:      102,
@@ -96,6 +98,8 @@ var _ = Describe("HaproxyClient", func() {
 
 				r8 := haproxy_client.HaproxyStat{
 					ProxyName:            "listen_cfg_60001",
+					ServerName:           "BACKEND",
+					Status:               "UP",
 					CurrentQueued:        1000,
 					CurrentSessions:      1001,
 					ErrorConnecting:      1002,
@@ -109,6 +113,59 @@ var _ = Describe("HaproxyClient", func() {
 			})
 		})
 
+		Context("when haproxy provides SSL statistics", func() {
+			BeforeEach(func() {
+				readyChannel := make(chan struct{})
+				csvPayload, err := ioutil.ReadFile("fixtures/testdata_ssl.csv")
+				Expect(err).NotTo(HaveOccurred())
+
+				go setupUnixSocketServer(csvPayload, haproxyUnixSocket, readyChannel)
+				haproxyClient = haproxy_client.NewClient(logger, haproxyUnixSocket, timeout)
+				Eventually(readyChannel).Should(BeClosed())
+			})
+
+			It("returns failed handshakes", func() {
+				stats := haproxyClient.GetStats()
+				Expect(stats).Should(HaveLen(9))
+				Expect(stats[3].ProxyName).To(Equal("listen_cfg_60000"))
+				Expect(stats[3].FailedHandshakes).To(Equal(uint64(7)))
+				Expect(stats[4].FailedHandshakes).To(BeZero())
+			})
+		})
+
+		Context("when haproxy provides denied connection statistics", func() {
+			BeforeEach(func() {
+				readyChannel := make(chan struct{})
+				csvPayload, err := ioutil.ReadFile("fixtures/testdata_denied.csv")
+				Expect(err).NotTo(HaveOccurred())
+
+				go setupUnixSocketServer(csvPayload, haproxyUnixSocket, readyChannel)
+				haproxyClient = haproxy_client.NewClient(logger, haproxyUnixSocket, timeout)
+				Eventually(readyChannel).Should(BeClosed())
+			})
+
+			It("returns denied connections", func() {
+				stats := haproxyClient.GetStats()
+				Expect(stats).Should(HaveLen(9))
+				Expect(stats[3].ProxyName).To(Equal("listen_cfg_60000"))
+				Expect(stats[3].DeniedConnections).To(Equal(uint64(5)))
+				Expect(stats[3].FailedHandshakes).To(BeZero())
+				Expect(stats[6].DeniedConnections).To(BeZero())
+			})
+
+			It("returns the sessions denied by rate limits apart from denied connections", func() {
+				stats := haproxyClient.GetStats()
+				Expect(stats[3].DeniedSessions).To(Equal(uint64(3)))
+				Expect(stats[6].DeniedSessions).To(BeZero())
+			})
+
+			It("returns the requests denied by held down ports", func() {
+				stats := haproxyClient.GetStats()
+				Expect(stats[6].DeniedRequests).To(Equal(uint64(7)))
+				Expect(stats[3].DeniedRequests).To(BeZero())
+			})
+		})
+
 		Context("when haproxy does not provide statistics", func() {
 			BeforeEach(func() {
 				readyChannel := make(chan struct{})
@@ -14,6 +14,12 @@ func (name ProxyValue) Send(proxyName string, value uint64) {
 	metrics.SendValue(proxyName+"."+string(name), float64(value), "Metric")
 }
 
+type BackendValue string
+
+func (name BackendValue) Send(proxyName string, serverName string, value uint64) {
+	metrics.SendValue(proxyName+"."+serverName+"."+string(name), float64(value), "Metric")
+}
+
 type ProxyDurationMs string
 
 func (name ProxyDurationMs) Send(proxyName string, duration uint64) {
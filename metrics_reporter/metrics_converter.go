@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"code.cloudfoundry.org/cf-tcp-router/configurer/haproxy"
 	"code.cloudfoundry.org/cf-tcp-router/metrics_reporter/haproxy_client"
 	"code.cloudfoundry.org/cf-tcp-router/models"
 )
@@ -18,24 +19,35 @@ func Convert(proxyStats haproxy_client.HaproxyStats) *MetricsReport {
 	var (
 		totalCurrentQueuedRequests   uint64
 		totalBackendConnectionErrors uint64
+		totalFailedHandshakes        uint64
+		totalDeniedConnections       uint64
+		totalRateLimitedConnections  uint64
+		totalHeldDownRejections      uint64
 		averageQueueTimeMs           uint64
 		averageConnectTimeMs         uint64
 		totalQueueTimeMs             uint64
 		totalConnectTimeMs           uint64
 		proxyStatsMap                map[models.RoutingKey]ProxyStats
+		backendStatsMap              map[BackendKey]BackendStats
 	)
 
 	proxyStatsMap = map[models.RoutingKey]ProxyStats{}
+	backendStatsMap = map[BackendKey]BackendStats{}
 
 	length := uint64(len(proxyStats))
 
 	for _, proxyStat := range proxyStats {
 		totalCurrentQueuedRequests += proxyStat.CurrentQueued
 		totalBackendConnectionErrors += proxyStat.ErrorConnecting
+		totalFailedHandshakes += proxyStat.FailedHandshakes
+		totalDeniedConnections += proxyStat.DeniedConnections
+		totalRateLimitedConnections += proxyStat.DeniedSessions
+		totalHeldDownRejections += frontendDeniedRequests(proxyStat)
 		totalConnectTimeMs += proxyStat.AverageConnectTimeMs
 		totalQueueTimeMs += proxyStat.AverageQueueTimeMs
 
 		populateProxyStats(proxyStat, proxyStatsMap)
+		populateBackendStats(proxyStat, backendStatsMap)
 	}
 	averageQueueTimeMs = totalQueueTimeMs / length
 	averageConnectTimeMs = totalConnectTimeMs / length
@@ -43,9 +55,14 @@ func Convert(proxyStats haproxy_client.HaproxyStats) *MetricsReport {
 	return &MetricsReport{
 		TotalCurrentQueuedRequests:   totalCurrentQueuedRequests,
 		TotalBackendConnectionErrors: totalBackendConnectionErrors,
+		TotalFailedHandshakes:        totalFailedHandshakes,
+		TotalDeniedConnections:       totalDeniedConnections,
+		TotalRateLimitedConnections:  totalRateLimitedConnections,
+		TotalHeldDownRejections:      totalHeldDownRejections,
 		AverageQueueTimeMs:           averageQueueTimeMs,
 		AverageConnectTimeMs:         averageConnectTimeMs,
 		ProxyMetrics:                 proxyStatsMap,
+		BackendMetrics:               backendStatsMap,
 	}
 }
 
@@ -58,23 +75,81 @@ func populateProxyStats(proxyStat haproxy_client.HaproxyStat, proxyStatsMap map[
 		v, _ := proxyStatsMap[key]
 		v.ConnectionTime += proxyStat.AverageConnectTimeMs
 		v.CurrentSessions += proxyStat.CurrentSessions
+		v.FailedHandshakes += proxyStat.FailedHandshakes
+		v.DeniedConnections += proxyStat.DeniedConnections
+		v.RateLimitedConnections += proxyStat.DeniedSessions
+		v.HeldDownRejections += frontendDeniedRequests(proxyStat)
+		v.CurrentQueued += proxyStat.CurrentQueued
 		proxyStatsMap[key] = v
 	}
 }
 
-// proxyname i.e.  listen_cfg_9001, listen_cfg_9002
+// Held down ports reject with a content rule, which HAProxy counts in dreq on
+// both the frontend and the backend of a listen section. Only the frontend is
+// counted.
+func frontendDeniedRequests(proxyStat haproxy_client.HaproxyStat) uint64 {
+	if proxyStat.ServerName != "FRONTEND" {
+		return 0
+	}
+	return proxyStat.DeniedRequests
+}
+
+func populateBackendStats(proxyStat haproxy_client.HaproxyStat, backendStatsMap map[BackendKey]BackendStats) {
+	switch proxyStat.ServerName {
+	case "", "FRONTEND", "BACKEND":
+		return
+	}
+	// status is e.g. "UP", "DOWN 1/2" or "no check"
+	if proxyStat.Status == "" || proxyStat.Status == "no check" {
+		return
+	}
+	if !isRoutedServer(proxyStat.ServerName) {
+		return
+	}
+	key, err := proxyKey(proxyStat.ProxyName)
+	if err == nil {
+		backendStatsMap[BackendKey{RoutingKey: key, ServerName: proxyStat.ServerName}] = BackendStats{
+			Up:            strings.HasPrefix(proxyStat.Status, "UP"),
+			CheckFailures: proxyStat.CheckFailures,
+		}
+	}
+}
+
+// proxyname i.e.  listen_cfg_9001, listen_cfg_9002. Proxies of the base
+// configuration are not reported per port.
 func proxyKey(proxy string) (models.RoutingKey, error) {
 	routingKey := models.RoutingKey{}
 
-	proxyNameParts := strings.Split(proxy, "_")
-	if len(proxyNameParts) != 3 {
+	if !strings.HasPrefix(proxy, haproxy.ListenConfigPrefix) {
 		return routingKey, errors.New("not a valid proxy name")
 	}
-
-	port, err := strconv.ParseUint(proxyNameParts[2], 10, 16)
-	if err != nil {
-		return routingKey, err
+	port, err := strconv.ParseUint(strings.TrimPrefix(proxy, haproxy.ListenConfigPrefix), 10, 16)
+	if err != nil || port == 0 {
+		return routingKey, errors.New("not a valid proxy name")
 	}
 	routingKey.Port = uint16(port)
 	return routingKey, nil
 }
+
+// servername i.e. server_10.0.0.1_61000, server_fd00--1_61000. Servers of the
+// base configuration are not reported per backend.
+func isRoutedServer(server string) bool {
+	if !strings.HasPrefix(server, "server_") {
+		return false
+	}
+	_, err := portSuffix(strings.TrimPrefix(server, "server_"))
+	return err == nil
+}
+
+// Returns the port after the last underscore of name.
+func portSuffix(name string) (uint16, error) {
+	i := strings.LastIndex(name, "_")
+	if i <= 0 {
+		return 0, errors.New("no port in name")
+	}
+	port, err := strconv.ParseUint(name[i+1:], 10, 16)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(port), nil
+}
@@ -18,7 +18,7 @@ var _ = Describe("Metrics Converter", func() {
 		Context("when aggregating multiple proxies", func() {
 			BeforeEach(func() {
 				stats = haproxy_client.HaproxyStats{
-					{ProxyName: "fake_pxname1_9000",
+					{ProxyName: "listen_cfg_9000",
 						CurrentQueued:        10,
 						ErrorConnecting:      20,
 						AverageQueueTimeMs:   30,
@@ -26,7 +26,7 @@ var _ = Describe("Metrics Converter", func() {
 						CurrentSessions:      15,
 						AverageSessionTimeMs: 9,
 					},
-					{ProxyName: "fake_pxname2_9001",
+					{ProxyName: "listen_cfg_9001",
 						CurrentQueued:        20,
 						ErrorConnecting:      20,
 						AverageQueueTimeMs:   0,
@@ -59,22 +59,98 @@ var _ = Describe("Metrics Converter", func() {
 				expectedProxyStats1 := metrics_reporter.ProxyStats{
 					ConnectionTime:  25,
 					CurrentSessions: 15,
+					CurrentQueued:   10,
 				}
 				expectedProxyKey2 := models.RoutingKey{Port: 9001}
 				expectedProxyStats2 := metrics_reporter.ProxyStats{
 					ConnectionTime:  40,
 					CurrentSessions: 15,
+					CurrentQueued:   20,
 				}
 				Expect(metrics.ProxyMetrics).Should(HaveKeyWithValue(expectedProxyKey1, expectedProxyStats1))
 				Expect(metrics.ProxyMetrics).Should(HaveKeyWithValue(expectedProxyKey2, expectedProxyStats2))
 			})
 		})
 
+		Context("when frontends terminate TLS", func() {
+			BeforeEach(func() {
+				stats = haproxy_client.HaproxyStats{
+					{ProxyName: "listen_cfg_9000", ServerName: "FRONTEND", FailedHandshakes: 3},
+					{ProxyName: "listen_cfg_9001", ServerName: "FRONTEND", FailedHandshakes: 4},
+				}
+				metrics = metrics_reporter.Convert(stats)
+			})
+
+			It("aggregates FailedHandshakes", func() {
+				Expect(metrics.TotalFailedHandshakes).To(Equal(uint64(7)))
+			})
+
+			It("gets failed handshakes per proxy", func() {
+				Expect(metrics.ProxyMetrics[models.RoutingKey{Port: 9000}].FailedHandshakes).To(Equal(uint64(3)))
+				Expect(metrics.ProxyMetrics[models.RoutingKey{Port: 9001}].FailedHandshakes).To(Equal(uint64(4)))
+			})
+		})
+
+		Context("when frontends deny connections", func() {
+			BeforeEach(func() {
+				stats = haproxy_client.HaproxyStats{
+					{ProxyName: "listen_cfg_9000", ServerName: "FRONTEND", DeniedConnections: 5, DeniedSessions: 4},
+					{ProxyName: "listen_cfg_9001", ServerName: "FRONTEND", DeniedConnections: 2, DeniedRequests: 3},
+					{ProxyName: "listen_cfg_9001", ServerName: "BACKEND", DeniedRequests: 3},
+				}
+				metrics = metrics_reporter.Convert(stats)
+			})
+
+			It("aggregates DeniedConnections", func() {
+				Expect(metrics.TotalDeniedConnections).To(Equal(uint64(7)))
+			})
+
+			It("gets denied connections per proxy", func() {
+				Expect(metrics.ProxyMetrics[models.RoutingKey{Port: 9000}].DeniedConnections).To(Equal(uint64(5)))
+				Expect(metrics.ProxyMetrics[models.RoutingKey{Port: 9001}].DeniedConnections).To(Equal(uint64(2)))
+			})
+
+			It("counts connections rejected by rate limits apart from denied connections", func() {
+				Expect(metrics.TotalRateLimitedConnections).To(Equal(uint64(4)))
+				Expect(metrics.ProxyMetrics[models.RoutingKey{Port: 9000}].RateLimitedConnections).To(Equal(uint64(4)))
+				Expect(metrics.ProxyMetrics[models.RoutingKey{Port: 9001}].RateLimitedConnections).To(BeZero())
+			})
+
+			It("counts connections rejected by held down ports once, apart from denied connections", func() {
+				Expect(metrics.TotalHeldDownRejections).To(Equal(uint64(3)))
+				Expect(metrics.ProxyMetrics[models.RoutingKey{Port: 9000}].HeldDownRejections).To(BeZero())
+				Expect(metrics.ProxyMetrics[models.RoutingKey{Port: 9001}].HeldDownRejections).To(Equal(uint64(3)))
+			})
+		})
+
+		Context("when backends are health checked", func() {
+			BeforeEach(func() {
+				stats = haproxy_client.HaproxyStats{
+					{ProxyName: "listen_cfg_9000", ServerName: "FRONTEND", Status: "OPEN"},
+					{ProxyName: "listen_cfg_9000", ServerName: "server_10.0.0.1_61000", Status: "UP"},
+					{ProxyName: "listen_cfg_9000", ServerName: "server_10.0.0.2_61000", Status: "DOWN 1/2", CheckFailures: 4},
+					{ProxyName: "listen_cfg_9000", ServerName: "server_fd00--1_61000", Status: "UP"},
+					{ProxyName: "stats", ServerName: "admin", Status: "UP"},
+					{ProxyName: "listen_cfg_9001", ServerName: "server_10.0.0.3_61000", Status: "no check"},
+					{ProxyName: "listen_cfg_9000", ServerName: "BACKEND", Status: "UP"},
+				}
+				metrics = metrics_reporter.Convert(stats)
+			})
+
+			It("gets health check stats per backend", func() {
+				Expect(metrics.BackendMetrics).To(Equal(map[metrics_reporter.BackendKey]metrics_reporter.BackendStats{
+					{RoutingKey: models.RoutingKey{Port: 9000}, ServerName: "server_10.0.0.1_61000"}: {Up: true},
+					{RoutingKey: models.RoutingKey{Port: 9000}, ServerName: "server_10.0.0.2_61000"}: {Up: false, CheckFailures: 4},
+					{RoutingKey: models.RoutingKey{Port: 9000}, ServerName: "server_fd00--1_61000"}:  {Up: true},
+				}))
+			})
+		})
+
 		Context("multiple services in single connection", func() {
 			BeforeEach(func() {
 				stats = haproxy_client.HaproxyStats{
 					{
-						ProxyName:            "fake_pxname1_9000",
+						ProxyName:            "listen_cfg_9000",
 						CurrentQueued:        10,
 						ErrorConnecting:      20,
 						AverageQueueTimeMs:   30,
@@ -83,7 +159,7 @@ var _ = Describe("Metrics Converter", func() {
 						AverageSessionTimeMs: 9,
 					},
 					{
-						ProxyName:            "fake_pxname2_9000",
+						ProxyName:            "listen_cfg_9000",
 						CurrentQueued:        20,
 						ErrorConnecting:      20,
 						AverageQueueTimeMs:   0,
@@ -100,6 +176,7 @@ var _ = Describe("Metrics Converter", func() {
 				expectedProxyStats1 := metrics_reporter.ProxyStats{
 					ConnectionTime:  65,
 					CurrentSessions: 30,
+					CurrentQueued:   30,
 				}
 
 				Expect(metrics.ProxyMetrics).Should(HaveKeyWithValue(expectedProxyKey1, expectedProxyStats1))
@@ -110,7 +187,7 @@ var _ = Describe("Metrics Converter", func() {
 			BeforeEach(func() {
 				stats = haproxy_client.HaproxyStats{
 					{
-						ProxyName:            "fake_pxname1_BAD",
+						ProxyName:            "listen_cfg_BAD",
 						CurrentQueued:        10,
 						ErrorConnecting:      20,
 						AverageQueueTimeMs:   30,
@@ -119,7 +196,7 @@ var _ = Describe("Metrics Converter", func() {
 						AverageSessionTimeMs: 9,
 					},
 					{
-						ProxyName:            "fake_pxname2_9001",
+						ProxyName:            "listen_cfg_9001",
 						CurrentQueued:        20,
 						ErrorConnecting:      20,
 						AverageQueueTimeMs:   0,
@@ -138,6 +215,7 @@ var _ = Describe("Metrics Converter", func() {
 				expectedProxyStats1 := metrics_reporter.ProxyStats{
 					ConnectionTime:  40,
 					CurrentSessions: 15,
+					CurrentQueued:   20,
 				}
 
 				Expect(len(metrics.ProxyMetrics)).Should(Equal(1))
@@ -159,7 +237,7 @@ var _ = Describe("Metrics Converter", func() {
 						AverageSessionTimeMs: 9,
 					},
 					{
-						ProxyName:            "fake_pxname2_9001",
+						ProxyName:            "listen_cfg_9001",
 						CurrentQueued:        20,
 						ErrorConnecting:      20,
 						AverageQueueTimeMs:   0,
@@ -178,6 +256,7 @@ var _ = Describe("Metrics Converter", func() {
 				expectedProxyStats1 := metrics_reporter.ProxyStats{
 					ConnectionTime:  40,
 					CurrentSessions: 15,
+					CurrentQueued:   20,
 				}
 
 				Expect(len(metrics.ProxyMetrics)).Should(Equal(1))
@@ -186,6 +265,23 @@ var _ = Describe("Metrics Converter", func() {
 			})
 		})
 
+		Context("proxies of the base configuration that end in a port", func() {
+			BeforeEach(func() {
+				stats = haproxy_client.HaproxyStats{
+					{ProxyName: "stats_8080", ServerName: "FRONTEND", CurrentSessions: 5, DeniedConnections: 3},
+					{ProxyName: "frontend_443", ServerName: "server_10.0.0.1_61000", Status: "UP", CurrentSessions: 5},
+					{ProxyName: "listen_cfg_9001", CurrentSessions: 15},
+				}
+				metrics = metrics_reporter.Convert(stats)
+			})
+
+			It("only reports the listen sections of routed ports", func() {
+				Expect(metrics.ProxyMetrics).Should(HaveLen(1))
+				Expect(metrics.ProxyMetrics).Should(HaveKey(models.RoutingKey{Port: 9001}))
+				Expect(metrics.BackendMetrics).Should(BeEmpty())
+			})
+		})
+
 		Context("empty haproxy stats", func() {
 			BeforeEach(func() {
 				stats = haproxy_client.HaproxyStats{}
@@ -3,11 +3,23 @@ package metrics_reporter
 var (
 	totalCurrentQueuedRequests   = Value("TotalCurrentQueuedRequests")
 	totalBackendConnectionErrors = Value("TotalBackendConnectionErrors")
+	totalFailedHandshakes        = Value("TotalFailedHandshakes")
+	totalDeniedConnections       = Value("TotalDeniedConnections")
+	totalRateLimitedConnections  = Value("TotalRateLimitedConnections")
+	totalHeldDownRejections      = Value("TotalHeldDownRejections")
 	averageQueueTimeMs           = DurationMs("AverageQueueTimeMs")
 	averageConnectTimeMs         = DurationMs("AverageConnectTimeMs")
 
-	connectionTime  = ProxyDurationMs("ConnectionTime")
-	currentSessions = ProxyValue("CurrentSessions")
+	connectionTime     = ProxyDurationMs("ConnectionTime")
+	currentSessions    = ProxyValue("CurrentSessions")
+	failedHandshakes   = ProxyValue("FailedHandshakes")
+	deniedConnections  = ProxyValue("DeniedConnections")
+	rateLimited        = ProxyValue("RateLimitedConnections")
+	heldDownRejections = ProxyValue("HeldDownRejections")
+	currentQueued      = ProxyValue("CurrentQueued")
+
+	backendUp            = BackendValue("Up")
+	backendCheckFailures = BackendValue("CheckFailures")
 )
 
 type MetricsEmitter interface {
@@ -24,11 +36,28 @@ func (e *metricsEmitter) Emit(r *MetricsReport) {
 	if r != nil {
 		totalCurrentQueuedRequests.Send(r.TotalCurrentQueuedRequests)
 		totalBackendConnectionErrors.Send(r.TotalBackendConnectionErrors)
+		totalFailedHandshakes.Send(r.TotalFailedHandshakes)
+		totalDeniedConnections.Send(r.TotalDeniedConnections)
+		totalRateLimitedConnections.Send(r.TotalRateLimitedConnections)
+		totalHeldDownRejections.Send(r.TotalHeldDownRejections)
 		averageQueueTimeMs.Send(r.AverageQueueTimeMs)
 		averageConnectTimeMs.Send(r.AverageConnectTimeMs)
 		for k, v := range r.ProxyMetrics {
 			connectionTime.Send(k.String(), v.ConnectionTime)
 			currentSessions.Send(k.String(), v.CurrentSessions)
+			failedHandshakes.Send(k.String(), v.FailedHandshakes)
+			deniedConnections.Send(k.String(), v.DeniedConnections)
+			rateLimited.Send(k.String(), v.RateLimitedConnections)
+			heldDownRejections.Send(k.String(), v.HeldDownRejections)
+			currentQueued.Send(k.String(), v.CurrentQueued)
+		}
+		for k, v := range r.BackendMetrics {
+			up := uint64(0)
+			if v.Up {
+				up = 1
+			}
+			backendUp.Send(k.RoutingKey.String(), k.ServerName, up)
+			backendCheckFailures.Send(k.RoutingKey.String(), k.ServerName, v.CheckFailures)
 		}
 	}
 }
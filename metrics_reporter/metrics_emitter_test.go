@@ -32,18 +32,31 @@ var _ = Describe("MetricsEmitter", func() {
 				metricsReport = metrics_reporter.MetricsReport{
 					TotalCurrentQueuedRequests:   10,
 					TotalBackendConnectionErrors: 1,
+					TotalFailedHandshakes:        6,
+					TotalDeniedConnections:       4,
+					TotalRateLimitedConnections:  8,
+					TotalHeldDownRejections:      9,
 					AverageQueueTimeMs:           100,
 					AverageConnectTimeMs:         1000,
 					ProxyMetrics: map[models.RoutingKey]metrics_reporter.ProxyStats{
 						models.RoutingKey{Port: 9000}: metrics_reporter.ProxyStats{
-							ConnectionTime:  10,
-							CurrentSessions: 50,
+							ConnectionTime:         10,
+							CurrentSessions:        50,
+							FailedHandshakes:       6,
+							DeniedConnections:      4,
+							RateLimitedConnections: 8,
+							HeldDownRejections:     9,
+							CurrentQueued:          10,
 						},
 						models.RoutingKey{Port: 8000}: metrics_reporter.ProxyStats{
 							ConnectionTime:  100,
 							CurrentSessions: 500,
 						},
 					},
+					BackendMetrics: map[metrics_reporter.BackendKey]metrics_reporter.BackendStats{
+						{RoutingKey: models.RoutingKey{Port: 9000}, ServerName: "server_10.0.0.1_61000"}: {Up: true},
+						{RoutingKey: models.RoutingKey{Port: 9000}, ServerName: "server_10.0.0.2_61000"}: {Up: false, CheckFailures: 3},
+					},
 				}
 			})
 
@@ -92,6 +105,63 @@ var _ = Describe("MetricsEmitter", func() {
 					return sender.GetValue("8000.CurrentSessions")
 				}).Should(Equal(fake.Metric{Value: float64(500), Unit: "Metric"}))
 			})
+
+			It("emits failed handshakes in total and for each port", func() {
+				Eventually(func() fake.Metric {
+					return sender.GetValue("TotalFailedHandshakes")
+				}).Should(Equal(fake.Metric{Value: float64(6), Unit: "Metric"}))
+				Eventually(func() fake.Metric {
+					return sender.GetValue("9000.FailedHandshakes")
+				}).Should(Equal(fake.Metric{Value: float64(6), Unit: "Metric"}))
+			})
+
+			It("emits denied connections in total and for each port", func() {
+				Eventually(func() fake.Metric {
+					return sender.GetValue("TotalDeniedConnections")
+				}).Should(Equal(fake.Metric{Value: float64(4), Unit: "Metric"}))
+				Eventually(func() fake.Metric {
+					return sender.GetValue("9000.DeniedConnections")
+				}).Should(Equal(fake.Metric{Value: float64(4), Unit: "Metric"}))
+			})
+
+			It("emits rate limited connections in total and for each port", func() {
+				Eventually(func() fake.Metric {
+					return sender.GetValue("TotalRateLimitedConnections")
+				}).Should(Equal(fake.Metric{Value: float64(8), Unit: "Metric"}))
+				Eventually(func() fake.Metric {
+					return sender.GetValue("9000.RateLimitedConnections")
+				}).Should(Equal(fake.Metric{Value: float64(8), Unit: "Metric"}))
+			})
+
+			It("emits connections rejected by held down ports in total and for each port", func() {
+				Eventually(func() fake.Metric {
+					return sender.GetValue("TotalHeldDownRejections")
+				}).Should(Equal(fake.Metric{Value: float64(9), Unit: "Metric"}))
+				Eventually(func() fake.Metric {
+					return sender.GetValue("9000.HeldDownRejections")
+				}).Should(Equal(fake.Metric{Value: float64(9), Unit: "Metric"}))
+			})
+
+			It("emits queued connections for each port", func() {
+				Eventually(func() fake.Metric {
+					return sender.GetValue("9000.CurrentQueued")
+				}).Should(Equal(fake.Metric{Value: float64(10), Unit: "Metric"}))
+				Eventually(func() fake.Metric {
+					return sender.GetValue("8000.CurrentQueued")
+				}).Should(Equal(fake.Metric{Value: float64(0), Unit: "Metric"}))
+			})
+
+			It("emits health check metrics for each backend", func() {
+				Eventually(func() fake.Metric {
+					return sender.GetValue("9000.server_10.0.0.1_61000.Up")
+				}).Should(Equal(fake.Metric{Value: float64(1), Unit: "Metric"}))
+				Eventually(func() fake.Metric {
+					return sender.GetValue("9000.server_10.0.0.2_61000.Up")
+				}).Should(Equal(fake.Metric{Value: float64(0), Unit: "Metric"}))
+				Eventually(func() fake.Metric {
+					return sender.GetValue("9000.server_10.0.0.2_61000.CheckFailures")
+				}).Should(Equal(fake.Metric{Value: float64(3), Unit: "Metric"}))
+			})
 		})
 
 		Context("when nil MetricsReport is passed", func() {
@@ -2,8 +2,10 @@ package metrics_reporter
 
 import (
 	"os"
+	"sync"
 	"time"
 
+	"code.cloudfoundry.org/cf-tcp-router/config"
 	"code.cloudfoundry.org/cf-tcp-router/metrics_reporter/haproxy_client"
 	"code.cloudfoundry.org/cf-tcp-router/models"
 	"code.cloudfoundry.org/clock"
@@ -12,14 +14,41 @@ import (
 type MetricsReport struct {
 	TotalCurrentQueuedRequests   uint64
 	TotalBackendConnectionErrors uint64
+	TotalFailedHandshakes        uint64
+	TotalDeniedConnections       uint64
+	TotalRateLimitedConnections  uint64
+	TotalHeldDownRejections      uint64
 	AverageQueueTimeMs           uint64
 	AverageConnectTimeMs         uint64
 	ProxyMetrics                 map[models.RoutingKey]ProxyStats
+	BackendMetrics               map[BackendKey]BackendStats
 }
 
 type ProxyStats struct {
-	ConnectionTime  uint64
-	CurrentSessions uint64
+	ConnectionTime    uint64
+	CurrentSessions   uint64
+	FailedHandshakes  uint64
+	DeniedConnections uint64
+	// RateLimitedConnections are the connections rejected by rate limits,
+	// which are not counted in DeniedConnections.
+	RateLimitedConnections uint64
+	// HeldDownRejections are the connections rejected by ports held down
+	// without backends, which are not counted in DeniedConnections.
+	HeldDownRejections uint64
+	CurrentQueued      uint64
+}
+
+// BackendKey identifies a server line of a listen section.
+type BackendKey struct {
+	RoutingKey models.RoutingKey
+	ServerName string
+}
+
+// BackendStats reports the health check state of a backend. Backends without
+// health checks are not reported.
+type BackendStats struct {
+	Up            bool
+	CheckFailures uint64
 }
 
 type MetricsReporter struct {
@@ -27,6 +56,9 @@ type MetricsReporter struct {
 	emitInterval   time.Duration
 	haproxyClient  haproxy_client.HaproxyClient
 	metricsEmitter MetricsEmitter
+
+	certificatesLock sync.Mutex
+	certificates     map[uint16]config.Certificate
 }
 
 func NewMetricsReporter(clock clock.Clock, haproxyClient haproxy_client.HaproxyClient, metricsEmitter MetricsEmitter, interval time.Duration) *MetricsReporter {
@@ -53,7 +85,20 @@ func (r *MetricsReporter) Run(signals <-chan os.Signal, ready chan<- struct{}) e
 	return nil
 }
 
+// UpdateCertificates replaces the certificates whose expiry is emitted on
+// every interval with the last loaded certificates.
+func (r *MetricsReporter) UpdateCertificates(certificates map[uint16]config.Certificate) {
+	r.certificatesLock.Lock()
+	defer r.certificatesLock.Unlock()
+	r.certificates = certificates
+}
+
 func (r *MetricsReporter) emitStats() {
+	r.certificatesLock.Lock()
+	certificates := r.certificates
+	r.certificatesLock.Unlock()
+	emitCertificateExpiries(certificates)
+
 	// get stats
 	stats := r.haproxyClient.GetStats()
 
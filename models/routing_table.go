@@ -2,7 +2,8 @@ package models
 
 import (
 	"fmt"
-	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"code.cloudfoundry.org/lager"
@@ -18,6 +19,8 @@ type BackendServerInfo struct {
 	Port            uint16
 	ModificationTag routing_api_models.ModificationTag
 	TTL             int
+	Weight          int
+	Draining        bool
 }
 
 type BackendServerKey struct {
@@ -25,19 +28,65 @@ type BackendServerKey struct {
 	Port    uint16
 }
 
+// A Weight of 0 leaves the load balancer default in place. A backend with a
+// DrainingSince time has been deleted and takes no new connections, but is
+// kept until its sessions end or the drain timeout of the table passes.
 type BackendServerDetails struct {
 	ModificationTag routing_api_models.ModificationTag
 	TTL             int
+	Weight          int
 	UpdatedTime     time.Time
+	DrainingSince   time.Time
 }
 
+// An entry without backends and with an EmptySince time is a port held bound
+// after losing its last backend.
 type RoutingTableEntry struct {
-	Backends map[BackendServerKey]BackendServerDetails
+	Backends   map[BackendServerKey]BackendServerDetails
+	EmptySince time.Time
 }
 
+// RoutingTable is safe for concurrent use. Entries are never modified in place
+// once stored; changes to a port replace its entry, so the entries handed out
+// by Get and Snapshot can be read without holding the lock.
+//
+// Every port carries a generation that changes whenever its entry changes in a
+// way that affects the routing configuration, so consumers can skip ports they
+// have already rendered.
+//
+// With a drain timeout, deleted backends are drained rather than removed. With
+// a hold down, ports that lose their last backend are kept without backends
+// until the hold down passes.
 type RoutingTable struct {
-	Entries map[RoutingKey]RoutingTableEntry
-	logger  lager.Logger
+	entries      map[RoutingKey]RoutingTableEntry
+	generations  map[RoutingKey]uint64
+	tombstones   map[tombstoneKey]BackendServerDetails
+	drainTimeout time.Duration
+	holdDown     time.Duration
+	lock         *sync.RWMutex
+	logger       lager.Logger
+}
+
+// RoutingTableSnapshot is a point in time view of a RoutingTable. It is not
+// affected by later changes to the table and must not be modified.
+type RoutingTableSnapshot struct {
+	Entries     map[RoutingKey]RoutingTableEntry
+	generations map[RoutingKey]uint64
+}
+
+// Generations are unique across all tables so that snapshots of different
+// tables never share a generation for different content.
+var lastGeneration uint64
+
+func nextGeneration() uint64 {
+	return atomic.AddUint64(&lastGeneration, 1)
+}
+
+// tombstoneKey identifies a deleted backend. Tombstones keep the modification
+// tag of the delete so that out of order upserts cannot resurrect the backend.
+type tombstoneKey struct {
+	routingKey RoutingKey
+	backendKey BackendServerKey
 }
 
 func NewRoutingTableEntry(backends []BackendServerInfo) RoutingTableEntry {
@@ -46,7 +95,7 @@ func NewRoutingTableEntry(backends []BackendServerInfo) RoutingTableEntry {
 	}
 	for _, backend := range backends {
 		backendServerKey := BackendServerKey{Address: backend.Address, Port: backend.Port}
-		backendServerDetails := BackendServerDetails{ModificationTag: backend.ModificationTag, TTL: backend.TTL, UpdatedTime: time.Now()}
+		backendServerDetails := BackendServerDetails{ModificationTag: backend.ModificationTag, TTL: backend.TTL, Weight: backend.Weight, UpdatedTime: time.Now()}
 
 		routingTableEntry.Backends[backendServerKey] = backendServerDetails
 	}
@@ -55,23 +104,86 @@ func NewRoutingTableEntry(backends []BackendServerInfo) RoutingTableEntry {
 
 func NewRoutingTable(logger lager.Logger) RoutingTable {
 	return RoutingTable{
-		Entries: make(map[RoutingKey]RoutingTableEntry),
-		logger:  logger.Session("routing-table"),
+		entries:     make(map[RoutingKey]RoutingTableEntry),
+		generations: make(map[RoutingKey]uint64),
+		tombstones:  make(map[tombstoneKey]BackendServerDetails),
+		lock:        new(sync.RWMutex),
+		logger:      logger.Session("routing-table"),
 	}
 }
 
+// WithDrainTimeout returns the table set to drain deleted backends for up to
+// drainTimeout. A drainTimeout of 0 removes them at once. Both tables share
+// their contents, so the original should no longer be used.
+func (table RoutingTable) WithDrainTimeout(drainTimeout time.Duration) RoutingTable {
+	table.drainTimeout = drainTimeout
+	return table
+}
+
+// WithEmptyPortHoldDown returns the table set to hold ports that lose their
+// last backend for holdDown. A holdDown of 0 removes them at once. Both tables
+// share their contents, so the original should no longer be used.
+func (table RoutingTable) WithEmptyPortHoldDown(holdDown time.Duration) RoutingTable {
+	table.holdDown = holdDown
+	return table
+}
+
+// HeldDown reports whether the entry is a port held after losing its last backend.
+func (e RoutingTableEntry) HeldDown() bool {
+	return len(e.Backends) == 0 && !e.EmptySince.IsZero()
+}
+
 func (e RoutingTableEntry) PruneBackends(defaultTTL int) {
+	e.pruneBackends(defaultTTL, time.Now())
+}
+
+// Draining backends are not pruned; they leave the table once drained.
+func (e RoutingTableEntry) pruneBackends(defaultTTL int, now time.Time) {
 	for backendKey, details := range e.Backends {
-		if details.Expired(defaultTTL) {
+		if !details.Draining() && details.expiredAt(defaultTTL, now) {
 			delete(e.Backends, backendKey)
 		}
 	}
 }
 
-// Used to determine whether the details have changed such that the routing configuration needs to be updated.
-// e.g max number of connection
+// Equal reports whether both entries hold the same backends with the same details.
+func (e RoutingTableEntry) Equal(other RoutingTableEntry) bool {
+	if len(e.Backends) != len(other.Backends) {
+		return false
+	}
+	for key, details := range e.Backends {
+		otherDetails, found := other.Backends[key]
+		if !found || !details.Equal(otherDetails) {
+			return false
+		}
+	}
+	return true
+}
+
+// Reports whether the routing configuration rendered from the entries would differ.
+func (e RoutingTableEntry) differentFrom(other RoutingTableEntry) bool {
+	if len(e.Backends) != len(other.Backends) {
+		return true
+	}
+	for key, details := range e.Backends {
+		otherDetails, found := other.Backends[key]
+		if !found || details.DifferentFrom(otherDetails) {
+			return true
+		}
+	}
+	return false
+}
+
+// DifferentFrom reports whether replacing d with other changes the rendered
+// routing configuration. Only the rendered details, the weight and whether the
+// backend is draining, are compared; the modification tag, TTL and update time
+// are bookkeeping and never require a reload.
 func (d BackendServerDetails) DifferentFrom(other BackendServerDetails) bool {
-	return d.UpdateSucceededBy(other) && false
+	return d.Weight != other.Weight || d.Draining() != other.Draining()
+}
+
+func (d BackendServerDetails) Draining() bool {
+	return !d.DrainingSince.IsZero()
 }
 
 func (d BackendServerDetails) UpdateSucceededBy(other BackendServerDetails) bool {
@@ -82,13 +194,25 @@ func (d BackendServerDetails) DeleteSucceededBy(other BackendServerDetails) bool
 	return d.ModificationTag == other.ModificationTag || d.ModificationTag.SucceededBy(&other.ModificationTag)
 }
 
+func (d BackendServerDetails) Equal(other BackendServerDetails) bool {
+	return d.ModificationTag == other.ModificationTag &&
+		d.TTL == other.TTL &&
+		d.Weight == other.Weight &&
+		d.UpdatedTime.Equal(other.UpdatedTime) &&
+		d.DrainingSince.Equal(other.DrainingSince)
+}
+
 func (d BackendServerDetails) Expired(defaultTTL int) bool {
+	return d.expiredAt(defaultTTL, time.Now())
+}
+
+func (d BackendServerDetails) expiredAt(defaultTTL int, now time.Time) bool {
 	ttl := d.TTL
 	if ttl == 0 {
 		ttl = defaultTTL
 	}
 
-	expiryTime := time.Now().Add(-time.Duration(ttl) * time.Second)
+	expiryTime := now.Add(-time.Duration(ttl) * time.Second)
 
 	return expiryTime.After(d.UpdatedTime)
 }
@@ -99,79 +223,252 @@ func NewBackendServerInfo(key BackendServerKey, detail BackendServerDetails) Bac
 		Port:            key.Port,
 		ModificationTag: detail.ModificationTag,
 		TTL:             detail.TTL,
+		Weight:          detail.Weight,
+		Draining:        detail.Draining(),
+	}
+}
+
+// Returns a copy of the entry without its expired backends, and whether any backend had expired.
+func (e RoutingTableEntry) withoutExpiredBackends(defaultTTL int, now time.Time) (RoutingTableEntry, bool) {
+	for _, details := range e.Backends {
+		if !details.Draining() && details.expiredAt(defaultTTL, now) {
+			pruned := e.Clone()
+			pruned.pruneBackends(defaultTTL, now)
+			return pruned, true
+		}
 	}
+	return e, false
 }
 
 func (table RoutingTable) PruneEntries(defaultTTL int) {
-	for routeKey, entry := range table.Entries {
-		entry.PruneBackends(defaultTTL)
-		if len(entry.Backends) == 0 {
-			delete(table.Entries, routeKey)
+	table.lock.Lock()
+	defer table.lock.Unlock()
+
+	now := time.Now()
+	for routeKey, entry := range table.entries {
+		pruned, changed := entry.withoutExpiredBackends(defaultTTL, now)
+		if !changed {
+			continue
 		}
+		if len(pruned.Backends) == 0 {
+			table.removeEntry(routeKey, now)
+		} else {
+			table.setEntry(routeKey, pruned, true)
+		}
+	}
+	table.pruneTombstones(defaultTTL, now)
+}
+
+func (table RoutingTable) pruneTombstones(defaultTTL int, now time.Time) {
+	for key, tombstone := range table.tombstones {
+		if tombstone.expiredAt(defaultTTL, now) {
+			delete(table.tombstones, key)
+		}
+	}
+}
+
+func (table RoutingTable) setEntry(key RoutingKey, entry RoutingTableEntry, changed bool) {
+	table.entries[key] = entry
+	if changed {
+		table.generations[key] = nextGeneration()
+	}
+}
+
+func (table RoutingTable) deleteEntry(key RoutingKey) {
+	delete(table.entries, key)
+	delete(table.generations, key)
+}
+
+// Removes a port that has no backends left, or holds it if the table has a hold down.
+func (table RoutingTable) removeEntry(key RoutingKey, now time.Time) {
+	if table.holdDown == 0 {
+		table.deleteEntry(key)
+		return
+	}
+	if entry, found := table.entries[key]; found && entry.HeldDown() {
+		return
 	}
+	table.logger.Debug("holding-empty-port", lager.Data{"key": key})
+	table.setEntry(key, RoutingTableEntry{Backends: map[BackendServerKey]BackendServerDetails{}, EmptySince: now}, true)
 }
 
-func (table RoutingTable) serverKeyDetailsFromInfo(info BackendServerInfo) (BackendServerKey, BackendServerDetails) {
-	return BackendServerKey{Address: info.Address, Port: info.Port}, BackendServerDetails{ModificationTag: info.ModificationTag, TTL: info.TTL, UpdatedTime: time.Now()}
+func serverKeyDetailsFromInfo(info BackendServerInfo, now time.Time) (BackendServerKey, BackendServerDetails) {
+	return BackendServerKey{Address: info.Address, Port: info.Port}, BackendServerDetails{ModificationTag: info.ModificationTag, TTL: info.TTL, Weight: info.Weight, UpdatedTime: now}
 }
 
 // Returns true if routing configuration should be modified, false if it should not.
 func (table RoutingTable) Set(key RoutingKey, newEntry RoutingTableEntry) bool {
-	existingEntry, ok := table.Entries[key]
-	if ok == true && reflect.DeepEqual(existingEntry, newEntry) {
+	table.lock.Lock()
+	defer table.lock.Unlock()
+
+	existingEntry, ok := table.entries[key]
+	if ok == true && existingEntry.Equal(newEntry) {
 		return false
 	}
-	table.Entries[key] = newEntry
+	table.setEntry(key, newEntry.Clone(), true)
+	return true
+}
+
+// Rebuild replaces the contents of the table with the given backends, as if they
+// had been upserted into an empty table, and returns the changes that were made.
+// Ports whose rendered configuration is unaffected keep their generation.
+func (table RoutingTable) Rebuild(backends map[RoutingKey][]BackendServerInfo) RoutingTableChangeset {
+	logger := table.logger.Session("rebuild")
+	now := time.Now()
+
+	table.lock.Lock()
+	defer table.lock.Unlock()
+
+	entries := make(map[RoutingKey]RoutingTableEntry, len(backends))
+	for key, infos := range backends {
+		entry := RoutingTableEntry{Backends: make(map[BackendServerKey]BackendServerDetails, len(infos))}
+		for _, info := range infos {
+			backendKey, details := serverKeyDetailsFromInfo(info, now)
+			if existingDetails, found := entry.Backends[backendKey]; found {
+				if existingDetails.UpdateSucceededBy(details) {
+					entry.Backends[backendKey] = details
+				}
+				continue
+			}
+			if !table.acceptTombstoned(key, backendKey, details) {
+				logger.Debug("skipping-stale-backend", lager.Data{"key": key, "info": info})
+				continue
+			}
+			entry.Backends[backendKey] = details
+		}
+		if len(entry.Backends) > 0 {
+			entries[key] = entry
+		}
+	}
+	table.keepDraining(entries)
+
+	previous := table.snapshot()
+	for key := range table.entries {
+		if _, found := entries[key]; !found {
+			table.removeEntry(key, now)
+		}
+	}
+	for key, entry := range entries {
+		existingEntry, found := table.entries[key]
+		table.setEntry(key, entry, !found || entry.differentFrom(existingEntry))
+	}
+	return previous.Diff(table.snapshot())
+}
+
+// Adds the draining backends of the table that are not in entries, so that a
+// sync does not cut their sessions.
+func (table RoutingTable) keepDraining(entries map[RoutingKey]RoutingTableEntry) {
+	for key, existingEntry := range table.entries {
+		for backendKey, details := range existingEntry.Backends {
+			if !details.Draining() {
+				continue
+			}
+			entry, found := entries[key]
+			if !found {
+				entry = RoutingTableEntry{Backends: make(map[BackendServerKey]BackendServerDetails)}
+				entries[key] = entry
+			}
+			if _, found := entry.Backends[backendKey]; !found {
+				entry.Backends[backendKey] = details
+			}
+		}
+	}
+}
+
+// Returns false if the backend was deleted by an event that the details do not
+// succeed. The tombstone is discarded once a newer event for the backend arrives.
+func (table RoutingTable) acceptTombstoned(key RoutingKey, backendKey BackendServerKey, details BackendServerDetails) bool {
+	deletedKey := tombstoneKey{routingKey: key, backendKey: backendKey}
+	tombstone, deleted := table.tombstones[deletedKey]
+	if !deleted {
+		return true
+	}
+	if !tombstone.UpdateSucceededBy(details) {
+		return false
+	}
+	delete(table.tombstones, deletedKey)
 	return true
 }
 
 // Returns true if routing configuration should be modified, false if it should not.
 func (table RoutingTable) UpsertBackendServerKey(key RoutingKey, info BackendServerInfo) bool {
+	table.lock.Lock()
+	defer table.lock.Unlock()
+
+	return table.upsertBackendServerKey(key, info)
+}
+
+func (table RoutingTable) upsertBackendServerKey(key RoutingKey, info BackendServerInfo) bool {
 	logger := table.logger.Session("upsert-backend", lager.Data{"key": key, "info": info})
 
-	existingEntry, routingKeyFound := table.Entries[key]
+	newBackendKey, newBackendDetails := serverKeyDetailsFromInfo(info, time.Now())
+	existingEntry, routingKeyFound := table.entries[key]
+	currentBackendDetails, backendFound := existingEntry.Backends[newBackendKey]
+
+	// a draining backend was deleted, so only events newer than the delete revive it
+	draining := backendFound && currentBackendDetails.Draining()
+	if (!backendFound || draining) && !table.acceptTombstoned(key, newBackendKey, newBackendDetails) {
+		logger.Debug("skipping-stale-event", lager.Data{"new": newBackendDetails})
+		return false
+	}
+
 	if !routingKeyFound {
 		logger.Debug("routing-key-not-found", lager.Data{"routing-key": key})
-		existingEntry = NewRoutingTableEntry([]BackendServerInfo{info})
-		table.Entries[key] = existingEntry
+		table.setEntry(key, NewRoutingTableEntry([]BackendServerInfo{info}), true)
 		return true
 	}
 
-	newBackendKey, newBackendDetails := table.serverKeyDetailsFromInfo(info)
-	currentBackendDetails, backendFound := existingEntry.Backends[newBackendKey]
-
 	detailData := lager.Data{"old": currentBackendDetails, "new": newBackendDetails}
-	if !backendFound ||
-		currentBackendDetails.UpdateSucceededBy(newBackendDetails) {
-		logger.Debug("applying-change-to-table", detailData)
-		existingEntry.Backends[newBackendKey] = newBackendDetails
-	} else {
+	if backendFound && !draining &&
+		!currentBackendDetails.UpdateSucceededBy(newBackendDetails) {
 		logger.Debug("skipping-stale-event", detailData)
+		return false
 	}
 
-	if !backendFound || currentBackendDetails.DifferentFrom(newBackendDetails) {
-		return true
-	}
-
-	return false
+	logger.Debug("applying-change-to-table", detailData)
+	changed := !backendFound || currentBackendDetails.DifferentFrom(newBackendDetails)
+	updatedEntry := existingEntry.Clone()
+	updatedEntry.EmptySince = time.Time{}
+	updatedEntry.Backends[newBackendKey] = newBackendDetails
+	table.setEntry(key, updatedEntry, changed)
+	return changed
 }
 
 // Returns true if routing configuration should be modified, false if it should not.
 func (table RoutingTable) DeleteBackendServerKey(key RoutingKey, info BackendServerInfo) bool {
+	table.lock.Lock()
+	defer table.lock.Unlock()
+
 	logger := table.logger.Session("delete-backend", lager.Data{"key": key, "info": info})
 
-	backendServerKey, newDetails := table.serverKeyDetailsFromInfo(info)
-	existingEntry, routingKeyFound := table.Entries[key]
+	backendServerKey, newDetails := serverKeyDetailsFromInfo(info, time.Now())
+	existingEntry, routingKeyFound := table.entries[key]
 
 	if routingKeyFound {
 		existingDetails, backendFound := existingEntry.Backends[backendServerKey]
 
 		detailData := lager.Data{"old": existingDetails, "new": newDetails}
 		if backendFound && existingDetails.DeleteSucceededBy(newDetails) {
+			table.tombstones[tombstoneKey{routingKey: key, backendKey: backendServerKey}] = newDetails
+			if existingDetails.Draining() {
+				logger.Debug("already-draining", detailData)
+				return false
+			}
+			if table.drainTimeout > 0 {
+				logger.Debug("draining-backend", detailData)
+				updatedEntry := existingEntry.Clone()
+				existingDetails.DrainingSince = newDetails.UpdatedTime
+				updatedEntry.Backends[backendServerKey] = existingDetails
+				table.setEntry(key, updatedEntry, true)
+				return true
+			}
 			logger.Debug("removing-from-table", detailData)
-			delete(existingEntry.Backends, backendServerKey)
-			if len(existingEntry.Backends) == 0 {
-				delete(table.Entries, key)
+			if len(existingEntry.Backends) == 1 {
+				table.removeEntry(key, newDetails.UpdatedTime)
+			} else {
+				updatedEntry := existingEntry.Clone()
+				delete(updatedEntry.Backends, backendServerKey)
+				table.setEntry(key, updatedEntry, true)
 			}
 			return true
 		} else {
@@ -182,12 +479,149 @@ func (table RoutingTable) DeleteBackendServerKey(key RoutingKey, info BackendSer
 	return false
 }
 
+// RemoveDrainedBackends removes draining backends that are idle or have been
+// draining for longer than the drain timeout. Returns true if routing
+// configuration should be modified.
+func (table RoutingTable) RemoveDrainedBackends(idle func(RoutingKey, BackendServerKey, BackendServerDetails) bool) bool {
+	table.lock.Lock()
+	defer table.lock.Unlock()
+
+	logger := table.logger.Session("remove-drained-backends")
+	now := time.Now()
+	changed := false
+	for key, entry := range table.entries {
+		var updatedEntry RoutingTableEntry
+		for backendKey, details := range entry.Backends {
+			if !details.Draining() {
+				continue
+			}
+			if now.Sub(details.DrainingSince) < table.drainTimeout && !idle(key, backendKey, details) {
+				continue
+			}
+			logger.Debug("removing-drained-backend", lager.Data{"key": key, "backend": backendKey})
+			if updatedEntry.Backends == nil {
+				updatedEntry = entry.Clone()
+			}
+			delete(updatedEntry.Backends, backendKey)
+		}
+		if updatedEntry.Backends == nil {
+			continue
+		}
+		changed = true
+		if len(updatedEntry.Backends) == 0 {
+			table.removeEntry(key, now)
+		} else {
+			table.setEntry(key, updatedEntry, true)
+		}
+	}
+	return changed
+}
+
+// ReleaseHeldPorts removes the ports that have been held for longer than the
+// hold down. Returns true if routing configuration should be modified.
+func (table RoutingTable) ReleaseHeldPorts() bool {
+	table.lock.Lock()
+	defer table.lock.Unlock()
+
+	logger := table.logger.Session("release-held-ports")
+	now := time.Now()
+	changed := false
+	for key, entry := range table.entries {
+		if !entry.HeldDown() || now.Sub(entry.EmptySince) < table.holdDown {
+			continue
+		}
+		logger.Debug("releasing-port", lager.Data{"key": key, "empty-since": entry.EmptySince})
+		table.deleteEntry(key)
+		changed = true
+	}
+	return changed
+}
+
+// Clone returns a deep copy of the table that shares no maps with the original.
+func (table RoutingTable) Clone() RoutingTable {
+	table.lock.RLock()
+	defer table.lock.RUnlock()
+
+	clone := RoutingTable{
+		entries:      make(map[RoutingKey]RoutingTableEntry, len(table.entries)),
+		generations:  make(map[RoutingKey]uint64, len(table.generations)),
+		tombstones:   make(map[tombstoneKey]BackendServerDetails, len(table.tombstones)),
+		drainTimeout: table.drainTimeout,
+		holdDown:     table.holdDown,
+		lock:         new(sync.RWMutex),
+		logger:       table.logger,
+	}
+	for key, entry := range table.entries {
+		clone.entries[key] = entry.Clone()
+	}
+	for key, generation := range table.generations {
+		clone.generations[key] = generation
+	}
+	for key, tombstone := range table.tombstones {
+		clone.tombstones[key] = tombstone
+	}
+	return clone
+}
+
+func (e RoutingTableEntry) Clone() RoutingTableEntry {
+	clone := RoutingTableEntry{
+		Backends:   make(map[BackendServerKey]BackendServerDetails, len(e.Backends)),
+		EmptySince: e.EmptySince,
+	}
+	for key, details := range e.Backends {
+		clone.Backends[key] = details
+	}
+	return clone
+}
+
+// Snapshot returns a point in time view of the table. Taking a snapshot only
+// copies the set of ports and their generations; the entries themselves are
+// shared, which is safe because the table replaces entries rather than
+// modifying them.
+func (table RoutingTable) Snapshot() RoutingTableSnapshot {
+	table.lock.RLock()
+	defer table.lock.RUnlock()
+
+	return table.snapshot()
+}
+
+func (table RoutingTable) snapshot() RoutingTableSnapshot {
+	entries := make(map[RoutingKey]RoutingTableEntry, len(table.entries))
+	generations := make(map[RoutingKey]uint64, len(table.generations))
+	for key, entry := range table.entries {
+		entries[key] = entry
+		generations[key] = table.generations[key]
+	}
+	return RoutingTableSnapshot{Entries: entries, generations: generations}
+}
+
 func (table RoutingTable) Get(key RoutingKey) RoutingTableEntry {
-	return table.Entries[key]
+	table.lock.RLock()
+	defer table.lock.RUnlock()
+
+	return table.entries[key]
 }
 
 func (table RoutingTable) Size() int {
-	return len(table.Entries)
+	table.lock.RLock()
+	defer table.lock.RUnlock()
+
+	return len(table.entries)
+}
+
+func (s RoutingTableSnapshot) Get(key RoutingKey) RoutingTableEntry {
+	return s.Entries[key]
+}
+
+func (s RoutingTableSnapshot) Size() int {
+	return len(s.Entries)
+}
+
+// Generation returns the generation of the entry for key, or 0 if the snapshot
+// does not track one. Equal non-zero generations guarantee equal rendered
+// configuration for the port.
+func (s RoutingTableSnapshot) Generation(key RoutingKey) uint64 {
+	return s.generations[key]
 }
 
 func (k RoutingKey) String() string {
@@ -0,0 +1,78 @@
+package models_test
+
+import (
+	"fmt"
+	"testing"
+
+	"code.cloudfoundry.org/cf-tcp-router/models"
+	"code.cloudfoundry.org/lager"
+	routing_api_models "code.cloudfoundry.org/routing-api/models"
+)
+
+const (
+	benchmarkPorts           = 1000
+	benchmarkBackendsPerPort = 100
+)
+
+func benchmarkBackends() map[models.RoutingKey][]models.BackendServerInfo {
+	backends := make(map[models.RoutingKey][]models.BackendServerInfo, benchmarkPorts)
+	for p := 0; p < benchmarkPorts; p++ {
+		key := models.RoutingKey{Port: uint16(50000 + p)}
+		infos := make([]models.BackendServerInfo, 0, benchmarkBackendsPerPort)
+		for b := 0; b < benchmarkBackendsPerPort; b++ {
+			infos = append(infos, models.BackendServerInfo{
+				Address:         fmt.Sprintf("10.0.%d.%d", p%256, b),
+				Port:            uint16(60000 + b),
+				ModificationTag: routing_api_models.ModificationTag{Guid: "guid", Index: 1},
+			})
+		}
+		backends[key] = infos
+	}
+	return backends
+}
+
+func benchmarkRoutingTable() models.RoutingTable {
+	routingTable := models.NewRoutingTable(lager.NewLogger("benchmark"))
+	routingTable.Rebuild(benchmarkBackends())
+	return routingTable
+}
+
+func BenchmarkRebuild(b *testing.B) {
+	backends := benchmarkBackends()
+	routingTable := models.NewRoutingTable(lager.NewLogger("benchmark"))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		routingTable.Rebuild(backends)
+	}
+}
+
+func BenchmarkUpsertBackendServerKey(b *testing.B) {
+	routingTable := benchmarkRoutingTable()
+	key := models.RoutingKey{Port: 50000}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		routingTable.UpsertBackendServerKey(key, models.BackendServerInfo{
+			Address:         "10.1.0.1",
+			Port:            61000,
+			ModificationTag: routing_api_models.ModificationTag{Guid: "guid", Index: uint32(i + 1)},
+		})
+	}
+}
+
+func BenchmarkSetUnchanged(b *testing.B) {
+	routingTable := benchmarkRoutingTable()
+	key := models.RoutingKey{Port: 50000}
+	entry := routingTable.Get(key)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		routingTable.Set(key, entry)
+	}
+}
+
+func BenchmarkSnapshot(b *testing.B) {
+	routingTable := benchmarkRoutingTable()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		routingTable.Snapshot()
+	}
+}
@@ -0,0 +1,113 @@
+package models
+
+import "sort"
+
+// RoutingTableChangeset describes the changes that turn one routing table into
+// another. Backends of added and removed ports are also listed in
+// AddedBackends and RemovedBackends. Updated backends carry their new details.
+type RoutingTableChangeset struct {
+	AddedPorts      []RoutingKey
+	RemovedPorts    []RoutingKey
+	AddedBackends   map[RoutingKey][]BackendServerInfo
+	RemovedBackends map[RoutingKey][]BackendServerInfo
+	UpdatedBackends map[RoutingKey][]BackendServerInfo
+}
+
+func NewRoutingTableChangeset() RoutingTableChangeset {
+	return RoutingTableChangeset{
+		AddedBackends:   make(map[RoutingKey][]BackendServerInfo),
+		RemovedBackends: make(map[RoutingKey][]BackendServerInfo),
+		UpdatedBackends: make(map[RoutingKey][]BackendServerInfo),
+	}
+}
+
+// Diff returns the changes from table to other. Backends are considered
+// updated when their modification tag, TTL or weight differ.
+func (table RoutingTable) Diff(other RoutingTable) RoutingTableChangeset {
+	return table.Snapshot().Diff(other.Snapshot())
+}
+
+// Diff returns the changes from snapshot s to other.
+func (s RoutingTableSnapshot) Diff(other RoutingTableSnapshot) RoutingTableChangeset {
+	changeset := NewRoutingTableChangeset()
+
+	for key, otherEntry := range other.Entries {
+		entry, found := s.Entries[key]
+		if !found {
+			changeset.AddedPorts = append(changeset.AddedPorts, key)
+		}
+		for backendKey, otherDetails := range otherEntry.Backends {
+			details, backendFound := entry.Backends[backendKey]
+			if !backendFound {
+				changeset.AddedBackends[key] = append(changeset.AddedBackends[key], NewBackendServerInfo(backendKey, otherDetails))
+			} else if details.ModificationTag != otherDetails.ModificationTag || details.TTL != otherDetails.TTL || details.Weight != otherDetails.Weight {
+				changeset.UpdatedBackends[key] = append(changeset.UpdatedBackends[key], NewBackendServerInfo(backendKey, otherDetails))
+			}
+		}
+	}
+
+	for key, entry := range s.Entries {
+		otherEntry, found := other.Entries[key]
+		if !found {
+			changeset.RemovedPorts = append(changeset.RemovedPorts, key)
+		}
+		for backendKey, details := range entry.Backends {
+			if _, backendFound := otherEntry.Backends[backendKey]; !backendFound {
+				changeset.RemovedBackends[key] = append(changeset.RemovedBackends[key], NewBackendServerInfo(backendKey, details))
+			}
+		}
+	}
+
+	changeset.sort()
+	return changeset
+}
+
+func (c RoutingTableChangeset) Empty() bool {
+	return len(c.AddedPorts) == 0 && len(c.RemovedPorts) == 0 &&
+		len(c.AddedBackends) == 0 && len(c.RemovedBackends) == 0 && len(c.UpdatedBackends) == 0
+}
+
+// ChangedPorts returns every port that has at least one change, in ascending order.
+func (c RoutingTableChangeset) ChangedPorts() []RoutingKey {
+	seen := make(map[RoutingKey]bool)
+	ports := []RoutingKey{}
+	for _, backends := range []map[RoutingKey][]BackendServerInfo{c.AddedBackends, c.RemovedBackends, c.UpdatedBackends} {
+		for key := range backends {
+			if !seen[key] {
+				seen[key] = true
+				ports = append(ports, key)
+			}
+		}
+	}
+	for _, key := range append(c.AddedPorts, c.RemovedPorts...) {
+		if !seen[key] {
+			seen[key] = true
+			ports = append(ports, key)
+		}
+	}
+	sortRoutingKeys(ports)
+	return ports
+}
+
+func (c RoutingTableChangeset) sort() {
+	sortRoutingKeys(c.AddedPorts)
+	sortRoutingKeys(c.RemovedPorts)
+	for _, backends := range []map[RoutingKey][]BackendServerInfo{c.AddedBackends, c.RemovedBackends, c.UpdatedBackends} {
+		for _, infos := range backends {
+			sortBackendServerInfos(infos)
+		}
+	}
+}
+
+func sortRoutingKeys(keys []RoutingKey) {
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Port < keys[j].Port })
+}
+
+func sortBackendServerInfos(infos []BackendServerInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Address != infos[j].Address {
+			return infos[i].Address < infos[j].Address
+		}
+		return infos[i].Port < infos[j].Port
+	})
+}
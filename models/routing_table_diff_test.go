@@ -0,0 +1,144 @@
+package models_test
+
+import (
+	"code.cloudfoundry.org/cf-tcp-router/models"
+	"code.cloudfoundry.org/lager/lagertest"
+	routing_api_models "code.cloudfoundry.org/routing-api/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RoutingTableDiff", func() {
+	var (
+		logger          = lagertest.NewTestLogger("routing-table-diff-test")
+		modificationTag routing_api_models.ModificationTag
+		oldTable        models.RoutingTable
+		newTable        models.RoutingTable
+	)
+
+	BeforeEach(func() {
+		modificationTag = routing_api_models.ModificationTag{Guid: "abc", Index: 1}
+		oldTable = models.NewRoutingTable(logger)
+		newTable = models.NewRoutingTable(logger)
+	})
+
+	Describe("Diff", func() {
+		Context("when the tables are the same", func() {
+			BeforeEach(func() {
+				entry := models.NewRoutingTableEntry([]models.BackendServerInfo{
+					models.BackendServerInfo{Address: "some-ip-1", Port: 1234, ModificationTag: modificationTag, TTL: 120},
+				})
+				Expect(oldTable.Set(models.RoutingKey{Port: 12}, entry)).To(BeTrue())
+				Expect(newTable.Set(models.RoutingKey{Port: 12}, entry.Clone())).To(BeTrue())
+			})
+
+			It("returns an empty changeset", func() {
+				changeset := oldTable.Diff(newTable)
+				Expect(changeset.Empty()).To(BeTrue())
+				Expect(changeset.ChangedPorts()).To(BeEmpty())
+			})
+		})
+
+		Context("when the tables differ", func() {
+			var (
+				updatedTag routing_api_models.ModificationTag
+			)
+
+			BeforeEach(func() {
+				updatedTag = modificationTag
+				updatedTag.Increment()
+
+				Expect(oldTable.Set(models.RoutingKey{Port: 12}, models.NewRoutingTableEntry([]models.BackendServerInfo{
+					models.BackendServerInfo{Address: "some-ip-1", Port: 1234, ModificationTag: modificationTag, TTL: 120},
+					models.BackendServerInfo{Address: "some-ip-2", Port: 1234, ModificationTag: modificationTag, TTL: 120},
+					models.BackendServerInfo{Address: "some-ip-3", Port: 1234, ModificationTag: modificationTag, TTL: 120},
+				}))).To(BeTrue())
+				Expect(oldTable.Set(models.RoutingKey{Port: 13}, models.NewRoutingTableEntry([]models.BackendServerInfo{
+					models.BackendServerInfo{Address: "some-ip-4", Port: 1234, ModificationTag: modificationTag, TTL: 120},
+				}))).To(BeTrue())
+
+				Expect(newTable.Set(models.RoutingKey{Port: 12}, models.NewRoutingTableEntry([]models.BackendServerInfo{
+					models.BackendServerInfo{Address: "some-ip-1", Port: 1234, ModificationTag: modificationTag, TTL: 120},
+					models.BackendServerInfo{Address: "some-ip-2", Port: 1234, ModificationTag: updatedTag, TTL: 120},
+					models.BackendServerInfo{Address: "some-ip-3", Port: 1234, ModificationTag: modificationTag, TTL: 60},
+					models.BackendServerInfo{Address: "some-ip-5", Port: 1234, ModificationTag: modificationTag, TTL: 120},
+				}))).To(BeTrue())
+				Expect(newTable.Set(models.RoutingKey{Port: 14}, models.NewRoutingTableEntry([]models.BackendServerInfo{
+					models.BackendServerInfo{Address: "some-ip-6", Port: 1234, ModificationTag: modificationTag, TTL: 120},
+				}))).To(BeTrue())
+			})
+
+			It("reports added and removed ports", func() {
+				changeset := oldTable.Diff(newTable)
+				Expect(changeset.Empty()).To(BeFalse())
+				Expect(changeset.AddedPorts).To(Equal([]models.RoutingKey{{Port: 14}}))
+				Expect(changeset.RemovedPorts).To(Equal([]models.RoutingKey{{Port: 13}}))
+			})
+
+			It("reports added, removed and updated backends", func() {
+				changeset := oldTable.Diff(newTable)
+				Expect(changeset.AddedBackends).To(Equal(map[models.RoutingKey][]models.BackendServerInfo{
+					models.RoutingKey{Port: 12}: {{Address: "some-ip-5", Port: 1234, ModificationTag: modificationTag, TTL: 120}},
+					models.RoutingKey{Port: 14}: {{Address: "some-ip-6", Port: 1234, ModificationTag: modificationTag, TTL: 120}},
+				}))
+				Expect(changeset.RemovedBackends).To(Equal(map[models.RoutingKey][]models.BackendServerInfo{
+					models.RoutingKey{Port: 13}: {{Address: "some-ip-4", Port: 1234, ModificationTag: modificationTag, TTL: 120}},
+				}))
+				Expect(changeset.UpdatedBackends).To(Equal(map[models.RoutingKey][]models.BackendServerInfo{
+					models.RoutingKey{Port: 12}: {
+						{Address: "some-ip-2", Port: 1234, ModificationTag: updatedTag, TTL: 120},
+						{Address: "some-ip-3", Port: 1234, ModificationTag: modificationTag, TTL: 60},
+					},
+				}))
+			})
+
+			It("lists every changed port", func() {
+				Expect(oldTable.Diff(newTable).ChangedPorts()).To(Equal([]models.RoutingKey{{Port: 12}, {Port: 13}, {Port: 14}}))
+			})
+		})
+
+		Context("when only the weight of a backend differs", func() {
+			BeforeEach(func() {
+				Expect(oldTable.Set(models.RoutingKey{Port: 12}, models.NewRoutingTableEntry([]models.BackendServerInfo{
+					models.BackendServerInfo{Address: "some-ip-1", Port: 1234, ModificationTag: modificationTag, TTL: 120, Weight: 10},
+				}))).To(BeTrue())
+				Expect(newTable.Set(models.RoutingKey{Port: 12}, models.NewRoutingTableEntry([]models.BackendServerInfo{
+					models.BackendServerInfo{Address: "some-ip-1", Port: 1234, ModificationTag: modificationTag, TTL: 120, Weight: 20},
+				}))).To(BeTrue())
+			})
+
+			It("reports the backend as updated", func() {
+				Expect(oldTable.Diff(newTable).UpdatedBackends).To(Equal(map[models.RoutingKey][]models.BackendServerInfo{
+					models.RoutingKey{Port: 12}: {{Address: "some-ip-1", Port: 1234, ModificationTag: modificationTag, TTL: 120, Weight: 20}},
+				}))
+			})
+		})
+	})
+
+	Describe("Clone", func() {
+		var routingKey models.RoutingKey
+
+		BeforeEach(func() {
+			routingKey = models.RoutingKey{Port: 12}
+			Expect(oldTable.Set(routingKey, models.NewRoutingTableEntry([]models.BackendServerInfo{
+				models.BackendServerInfo{Address: "some-ip-1", Port: 1234, ModificationTag: modificationTag, TTL: 120},
+			}))).To(BeTrue())
+		})
+
+		It("returns an equal table", func() {
+			clone := oldTable.Clone()
+			Expect(clone.Snapshot()).To(Equal(oldTable.Snapshot()))
+			Expect(oldTable.Diff(clone).Empty()).To(BeTrue())
+		})
+
+		It("does not share maps with the original", func() {
+			clone := oldTable.Clone()
+			Expect(oldTable.UpsertBackendServerKey(routingKey, models.BackendServerInfo{Address: "some-ip-2", Port: 1234, ModificationTag: modificationTag})).To(BeTrue())
+			Expect(oldTable.UpsertBackendServerKey(models.RoutingKey{Port: 13}, models.BackendServerInfo{Address: "some-ip-3", Port: 1234, ModificationTag: modificationTag})).To(BeTrue())
+
+			Expect(clone.Size()).To(Equal(1))
+			Expect(clone.Get(routingKey).Backends).To(HaveLen(1))
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package models_test
 
 import (
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/cf-tcp-router/models"
@@ -9,6 +10,7 @@ import (
 	routing_api_models "code.cloudfoundry.org/routing-api/models"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
 )
@@ -220,6 +222,26 @@ var _ = Describe("RoutingTable", func() {
 					updated := routingTable.UpsertBackendServerKey(routingKey, sameBackendServerInfo)
 					Expect(updated).To(BeFalse())
 				})
+
+				Context("when the TTL of the backend changes", func() {
+					It("updates the routing entry without updating routing configuration", func() {
+						newBackendServerInfo := createBackendServerInfo("some-ip", 1234, modificationTag)
+						newBackendServerInfo.TTL = 10
+						updated := routingTable.UpsertBackendServerKey(routingKey, newBackendServerInfo)
+						Expect(updated).To(BeFalse())
+						Expect(routingTable.Get(routingKey).Backends[models.BackendServerKey{Address: "some-ip", Port: 1234}].TTL).To(Equal(10))
+					})
+				})
+
+				Context("when the weight of the backend changes", func() {
+					It("updates routing configuration", func() {
+						reweightedBackendServerInfo := createBackendServerInfo("some-ip", 1234, modificationTag)
+						reweightedBackendServerInfo.Weight = 10
+						updated := routingTable.UpsertBackendServerKey(routingKey, reweightedBackendServerInfo)
+						Expect(updated).To(BeTrue())
+						Expect(routingTable.Get(routingKey).Backends[models.BackendServerKey{Address: "some-ip", Port: 1234}].Weight).To(Equal(10))
+					})
+				})
 			})
 
 			Context("and a new backend is provided", func() {
@@ -252,6 +274,14 @@ var _ = Describe("RoutingTable", func() {
 					Expect(logger).To(gbytes.Say("skipping-stale-event"))
 					testutil.RoutingTableEntryMatches(routingTable.Get(routingKey), existingRoutingTableEntry)
 				})
+
+				It("should not update routing configuration even if the weight differs", func() {
+					newBackendServerInfo := createBackendServerInfo("some-ip", 1234, modificationTag)
+					newBackendServerInfo.Weight = 10
+					updated := routingTable.UpsertBackendServerKey(routingKey, newBackendServerInfo)
+					Expect(updated).To(BeFalse())
+					testutil.RoutingTableEntryMatches(routingTable.Get(routingKey), existingRoutingTableEntry)
+				})
 			})
 		})
 	})
@@ -330,6 +360,59 @@ var _ = Describe("RoutingTable", func() {
 					})
 				})
 
+				Context("when an out of order upsert for the deleted backend arrives", func() {
+					BeforeEach(func() {
+						updated := routingTable.DeleteBackendServerKey(routingKey, backendServerInfo1)
+						Expect(updated).To(BeTrue())
+					})
+
+					It("does not resurrect the backend when the upsert is older than the delete", func() {
+						staleBackendServerInfo := backendServerInfo1
+						staleBackendServerInfo.ModificationTag.Index--
+						updated := routingTable.UpsertBackendServerKey(routingKey, staleBackendServerInfo)
+						Expect(updated).To(BeFalse())
+						Expect(logger).To(gbytes.Say("skipping-stale-event"))
+						expectedRoutingTableEntry := models.NewRoutingTableEntry([]models.BackendServerInfo{backendServerInfo2})
+						testutil.RoutingTableEntryMatches(routingTable.Get(routingKey), expectedRoutingTableEntry)
+					})
+
+					It("does not resurrect the backend when the upsert carries the deleted modification tag", func() {
+						updated := routingTable.UpsertBackendServerKey(routingKey, backendServerInfo1)
+						Expect(updated).To(BeFalse())
+						Expect(routingTable.Get(routingKey).Backends).To(HaveLen(1))
+					})
+
+					It("adds the backend back when the upsert succeeds the delete", func() {
+						newBackendServerInfo := backendServerInfo1
+						newBackendServerInfo.ModificationTag.Increment()
+						updated := routingTable.UpsertBackendServerKey(routingKey, newBackendServerInfo)
+						Expect(updated).To(BeTrue())
+						Expect(routingTable.Get(routingKey).Backends).To(HaveLen(2))
+					})
+
+					Context("when the last backend of the routing key was deleted", func() {
+						BeforeEach(func() {
+							updated := routingTable.DeleteBackendServerKey(routingKey, backendServerInfo2)
+							Expect(updated).To(BeTrue())
+						})
+
+						It("does not recreate the routing key", func() {
+							updated := routingTable.UpsertBackendServerKey(routingKey, backendServerInfo2)
+							Expect(updated).To(BeFalse())
+							Expect(routingTable.Size()).To(Equal(0))
+						})
+					})
+
+					Context("when the tombstone has been pruned", func() {
+						It("accepts the upsert", func() {
+							Eventually(func() bool {
+								routingTable.PruneEntries(0)
+								return routingTable.UpsertBackendServerKey(routingKey, backendServerInfo1)
+							}).Should(BeTrue())
+						})
+					})
+				})
+
 				Context("when there are no more backends left", func() {
 					BeforeEach(func() {
 						updated := routingTable.DeleteBackendServerKey(routingKey, backendServerInfo1)
@@ -346,6 +429,202 @@ var _ = Describe("RoutingTable", func() {
 		})
 	})
 
+	Describe("draining deleted backends", func() {
+		var (
+			routingKey         models.RoutingKey
+			backendServerInfo1 models.BackendServerInfo
+			backendServerInfo2 models.BackendServerInfo
+			backendServerKey1  models.BackendServerKey
+			idle               map[models.BackendServerKey]bool
+		)
+
+		isIdle := func(key models.RoutingKey, backend models.BackendServerKey, details models.BackendServerDetails) bool {
+			return idle[backend]
+		}
+
+		BeforeEach(func() {
+			routingTable = routingTable.WithDrainTimeout(time.Hour)
+			routingKey = models.RoutingKey{Port: 12}
+			backendServerInfo1 = createBackendServerInfo("some-ip", 1234, modificationTag)
+			backendServerInfo2 = createBackendServerInfo("some-other-ip", 1235, modificationTag)
+			backendServerKey1 = models.BackendServerKey{Address: "some-ip", Port: 1234}
+			idle = map[models.BackendServerKey]bool{}
+			routingTable.Set(routingKey, models.NewRoutingTableEntry([]models.BackendServerInfo{backendServerInfo1, backendServerInfo2}))
+
+			updated := routingTable.DeleteBackendServerKey(routingKey, backendServerInfo1)
+			Expect(updated).To(BeTrue())
+		})
+
+		It("keeps the deleted backend as draining", func() {
+			Expect(logger).To(gbytes.Say("draining-backend"))
+			Expect(routingTable.Get(routingKey).Backends).To(HaveLen(2))
+			Expect(routingTable.Get(routingKey).Backends[backendServerKey1].Draining()).To(BeTrue())
+		})
+
+		It("ignores further deletes of the draining backend", func() {
+			updated := routingTable.DeleteBackendServerKey(routingKey, backendServerInfo1)
+			Expect(updated).To(BeFalse())
+		})
+
+		It("keeps a port whose backends are all draining", func() {
+			updated := routingTable.DeleteBackendServerKey(routingKey, backendServerInfo2)
+			Expect(updated).To(BeTrue())
+			Expect(routingTable.Size()).To(Equal(1))
+		})
+
+		It("does not prune the draining backend when its TTL expires", func() {
+			Eventually(func() int {
+				routingTable.PruneEntries(0)
+				return routingTable.Size()
+			}).Should(Equal(1))
+			Expect(routingTable.Get(routingKey).Backends).To(HaveKey(backendServerKey1))
+			Expect(routingTable.Get(routingKey).Backends).To(HaveLen(1))
+		})
+
+		It("reactivates the backend when an upsert succeeds the delete", func() {
+			newBackendServerInfo := backendServerInfo1
+			newBackendServerInfo.ModificationTag.Increment()
+			updated := routingTable.UpsertBackendServerKey(routingKey, newBackendServerInfo)
+			Expect(updated).To(BeTrue())
+			Expect(routingTable.Get(routingKey).Backends[backendServerKey1].Draining()).To(BeFalse())
+		})
+
+		It("does not reactivate the backend with an upsert older than the delete", func() {
+			updated := routingTable.UpsertBackendServerKey(routingKey, backendServerInfo1)
+			Expect(updated).To(BeFalse())
+			Expect(routingTable.Get(routingKey).Backends[backendServerKey1].Draining()).To(BeTrue())
+		})
+
+		It("keeps draining backends that a sync no longer lists", func() {
+			routingTable.Rebuild(map[models.RoutingKey][]models.BackendServerInfo{
+				routingKey: {backendServerInfo1, backendServerInfo2},
+			})
+			Expect(routingTable.Get(routingKey).Backends[backendServerKey1].Draining()).To(BeTrue())
+
+			routingTable.Rebuild(map[models.RoutingKey][]models.BackendServerInfo{})
+			Expect(routingTable.Get(routingKey).Backends).To(HaveLen(1))
+			Expect(routingTable.Get(routingKey).Backends[backendServerKey1].Draining()).To(BeTrue())
+		})
+
+		Describe("RemoveDrainedBackends", func() {
+			It("keeps draining backends with sessions", func() {
+				Expect(routingTable.RemoveDrainedBackends(isIdle)).To(BeFalse())
+				Expect(routingTable.Get(routingKey).Backends).To(HaveLen(2))
+			})
+
+			It("removes draining backends without sessions", func() {
+				idle[backendServerKey1] = true
+				idle[models.BackendServerKey{Address: "some-other-ip", Port: 1235}] = true
+				Expect(routingTable.RemoveDrainedBackends(isIdle)).To(BeTrue())
+				Expect(routingTable.Get(routingKey).Backends).To(HaveLen(1))
+				Expect(routingTable.Get(routingKey).Backends).NotTo(HaveKey(backendServerKey1))
+			})
+
+			It("removes draining backends once the drain timeout passes", func() {
+				routingTable = routingTable.WithDrainTimeout(time.Nanosecond)
+				Expect(routingTable.RemoveDrainedBackends(isIdle)).To(BeTrue())
+				Expect(routingTable.Get(routingKey).Backends).To(HaveLen(1))
+			})
+
+			It("removes the port with its last drained backend", func() {
+				routingTable.DeleteBackendServerKey(routingKey, backendServerInfo2)
+				routingTable = routingTable.WithDrainTimeout(time.Nanosecond)
+				Expect(routingTable.RemoveDrainedBackends(isIdle)).To(BeTrue())
+				Expect(routingTable.Size()).To(Equal(0))
+			})
+
+			It("changes the generation of the port", func() {
+				generation := routingTable.Snapshot().Generation(routingKey)
+				idle[backendServerKey1] = true
+				routingTable.RemoveDrainedBackends(isIdle)
+				Expect(routingTable.Snapshot().Generation(routingKey)).NotTo(Equal(generation))
+			})
+		})
+	})
+
+	Describe("holding empty ports", func() {
+		var (
+			routingKey        models.RoutingKey
+			backendServerInfo models.BackendServerInfo
+		)
+
+		BeforeEach(func() {
+			routingTable = routingTable.WithEmptyPortHoldDown(time.Hour)
+			routingKey = models.RoutingKey{Port: 12}
+			backendServerInfo = createBackendServerInfo("some-ip", 1234, modificationTag)
+			routingTable.Set(routingKey, models.NewRoutingTableEntry([]models.BackendServerInfo{backendServerInfo}))
+		})
+
+		It("holds the port when its last backend is deleted", func() {
+			generation := routingTable.Snapshot().Generation(routingKey)
+			Expect(routingTable.DeleteBackendServerKey(routingKey, backendServerInfo)).To(BeTrue())
+			Expect(routingTable.Size()).To(Equal(1))
+			Expect(routingTable.Get(routingKey).HeldDown()).To(BeTrue())
+			Expect(routingTable.Get(routingKey).Backends).To(BeEmpty())
+			Expect(routingTable.Snapshot().Generation(routingKey)).NotTo(Equal(generation))
+		})
+
+		It("holds the port when its last backend is pruned", func() {
+			Eventually(func() bool {
+				routingTable.PruneEntries(0)
+				return routingTable.Get(routingKey).HeldDown()
+			}).Should(BeTrue())
+			Expect(routingTable.Size()).To(Equal(1))
+		})
+
+		It("keeps holding a port that a sync does not list", func() {
+			routingTable.DeleteBackendServerKey(routingKey, backendServerInfo)
+			emptySince := routingTable.Get(routingKey).EmptySince
+			generation := routingTable.Snapshot().Generation(routingKey)
+
+			changeset := routingTable.Rebuild(map[models.RoutingKey][]models.BackendServerInfo{})
+			Expect(changeset.Empty()).To(BeTrue())
+			Expect(routingTable.Get(routingKey).HeldDown()).To(BeTrue())
+			Expect(routingTable.Get(routingKey).EmptySince).To(Equal(emptySince))
+			Expect(routingTable.Snapshot().Generation(routingKey)).To(Equal(generation))
+		})
+
+		It("holds a port that a sync no longer lists", func() {
+			routingTable.Rebuild(map[models.RoutingKey][]models.BackendServerInfo{})
+			Expect(routingTable.Get(routingKey).HeldDown()).To(BeTrue())
+		})
+
+		It("recovers a held port when a backend is added", func() {
+			routingTable.DeleteBackendServerKey(routingKey, backendServerInfo)
+			newBackendServerInfo := createBackendServerInfo("some-other-ip", 1235, modificationTag)
+			Expect(routingTable.UpsertBackendServerKey(routingKey, newBackendServerInfo)).To(BeTrue())
+			Expect(routingTable.Get(routingKey).HeldDown()).To(BeFalse())
+			Expect(routingTable.Get(routingKey).EmptySince).To(BeZero())
+			Expect(routingTable.Get(routingKey).Backends).To(HaveLen(1))
+		})
+
+		Describe("ReleaseHeldPorts", func() {
+			BeforeEach(func() {
+				routingTable.DeleteBackendServerKey(routingKey, backendServerInfo)
+			})
+
+			It("keeps ports within the hold down", func() {
+				Expect(routingTable.ReleaseHeldPorts()).To(BeFalse())
+				Expect(routingTable.Size()).To(Equal(1))
+			})
+
+			It("removes ports once the hold down passes", func() {
+				routingTable = routingTable.WithEmptyPortHoldDown(time.Nanosecond)
+				Expect(routingTable.ReleaseHeldPorts()).To(BeTrue())
+				Expect(routingTable.Size()).To(Equal(0))
+			})
+
+			It("does not remove ports with backends", func() {
+				otherKey := models.RoutingKey{Port: 13}
+				routingTable.Set(otherKey, models.NewRoutingTableEntry([]models.BackendServerInfo{backendServerInfo}))
+				routingTable = routingTable.WithEmptyPortHoldDown(time.Nanosecond)
+				routingTable.ReleaseHeldPorts()
+				Expect(routingTable.Size()).To(Equal(1))
+				Expect(routingTable.Get(otherKey).Backends).To(HaveLen(1))
+			})
+		})
+	})
+
 	Describe("PruneEntries", func() {
 		var (
 			defaultTTL  int
@@ -390,7 +669,7 @@ var _ = Describe("RoutingTable", func() {
 			})
 
 			It("prunes the expired entries", func() {
-				Expect(routingTable.Entries).To(HaveLen(2))
+				Expect(routingTable.Size()).To(Equal(2))
 				Expect(routingTable.Get(routingKey1).Backends).To(HaveLen(1))
 				Expect(routingTable.Get(routingKey2).Backends).To(HaveLen(1))
 			})
@@ -401,7 +680,7 @@ var _ = Describe("RoutingTable", func() {
 				})
 
 				It("prunes the expired entries and deletes the routing key", func() {
-					Expect(routingTable.Entries).To(HaveLen(1))
+					Expect(routingTable.Size()).To(Equal(1))
 					Expect(routingTable.Get(routingKey2).Backends).To(HaveLen(1))
 				})
 			})
@@ -413,19 +692,196 @@ var _ = Describe("RoutingTable", func() {
 			})
 
 			It("does not prune entries", func() {
-				Expect(routingTable.Entries).To(HaveLen(2))
+				Expect(routingTable.Size()).To(Equal(2))
 				Expect(routingTable.Get(routingKey1).Backends).To(HaveLen(2))
 				Expect(routingTable.Get(routingKey2).Backends).To(HaveLen(2))
 			})
 		})
 	})
 
+	Describe("Snapshot", func() {
+		var routingKey models.RoutingKey
+
+		BeforeEach(func() {
+			routingKey = models.RoutingKey{Port: 12}
+			backendServerInfo := createBackendServerInfo("some-ip-1", 1234, modificationTag)
+			Expect(routingTable.UpsertBackendServerKey(routingKey, backendServerInfo)).To(BeTrue())
+		})
+
+		It("returns the current entries", func() {
+			snapshot := routingTable.Snapshot()
+			Expect(snapshot.Size()).To(Equal(1))
+			testutil.RoutingTableEntryMatches(snapshot.Get(routingKey), routingTable.Get(routingKey))
+		})
+
+		It("is not affected by later changes to the table", func() {
+			snapshot := routingTable.Snapshot()
+
+			newModificationTag := modificationTag
+			newModificationTag.Increment()
+			Expect(routingTable.UpsertBackendServerKey(routingKey, createBackendServerInfo("some-ip-2", 1234, modificationTag))).To(BeTrue())
+			Expect(routingTable.UpsertBackendServerKey(models.RoutingKey{Port: 13}, createBackendServerInfo("some-ip-3", 1234, modificationTag))).To(BeTrue())
+			Expect(routingTable.DeleteBackendServerKey(routingKey, createBackendServerInfo("some-ip-1", 1234, newModificationTag))).To(BeTrue())
+			routingTable.PruneEntries(0)
+
+			Expect(snapshot.Size()).To(Equal(1))
+			Expect(snapshot.Get(routingKey).Backends).To(HaveLen(1))
+			Expect(snapshot.Get(routingKey).Backends).To(HaveKey(models.BackendServerKey{Address: "some-ip-1", Port: 1234}))
+		})
+
+		Context("when the table is used concurrently", func() {
+			It("does not race", func() {
+				done := make(chan struct{})
+				wg := sync.WaitGroup{}
+				run := func(f func(i int)) {
+					wg.Add(1)
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+						for i := 0; ; i++ {
+							select {
+							case <-done:
+								return
+							default:
+								f(i)
+							}
+						}
+					}()
+				}
+
+				run(func(i int) {
+					tag := routing_api_models.ModificationTag{Guid: "abc", Index: uint32(i)}
+					routingTable.UpsertBackendServerKey(models.RoutingKey{Port: uint16(i%10 + 1)}, createBackendServerInfo("some-ip", uint16(i%100+1), tag))
+				})
+				run(func(i int) {
+					tag := routing_api_models.ModificationTag{Guid: "abc", Index: uint32(i)}
+					routingTable.DeleteBackendServerKey(models.RoutingKey{Port: uint16(i%10 + 1)}, createBackendServerInfo("some-ip", uint16(i%100+1), tag))
+				})
+				run(func(i int) {
+					routingTable.PruneEntries(60)
+				})
+				run(func(i int) {
+					routingTable.Rebuild(map[models.RoutingKey][]models.BackendServerInfo{
+						models.RoutingKey{Port: 1}: {createBackendServerInfo("some-ip", 1, modificationTag)},
+					})
+				})
+				run(func(i int) {
+					snapshot := routingTable.Snapshot()
+					for _, entry := range snapshot.Entries {
+						for range entry.Backends {
+						}
+					}
+				})
+
+				time.Sleep(100 * time.Millisecond)
+				close(done)
+				wg.Wait()
+			})
+		})
+	})
+
+	Describe("Rebuild", func() {
+		var routingKey models.RoutingKey
+
+		BeforeEach(func() {
+			routingKey = models.RoutingKey{Port: 12}
+			Expect(routingTable.UpsertBackendServerKey(routingKey, createBackendServerInfo("some-ip-1", 1234, modificationTag))).To(BeTrue())
+		})
+
+		It("replaces the entries and returns the changes", func() {
+			changeset := routingTable.Rebuild(map[models.RoutingKey][]models.BackendServerInfo{
+				models.RoutingKey{Port: 13}: {createBackendServerInfo("some-ip-2", 1234, modificationTag)},
+			})
+			Expect(routingTable.Size()).To(Equal(1))
+			Expect(routingTable.Get(models.RoutingKey{Port: 13}).Backends).To(HaveLen(1))
+			Expect(changeset.AddedPorts).To(Equal([]models.RoutingKey{{Port: 13}}))
+			Expect(changeset.RemovedPorts).To(Equal([]models.RoutingKey{routingKey}))
+		})
+
+		It("keeps the generation of ports that did not change", func() {
+			generation := routingTable.Snapshot().Generation(routingKey)
+			routingTable.Rebuild(map[models.RoutingKey][]models.BackendServerInfo{
+				routingKey:                  {createBackendServerInfo("some-ip-1", 1234, modificationTag)},
+				models.RoutingKey{Port: 13}: {createBackendServerInfo("some-ip-2", 1234, modificationTag)},
+			})
+			Expect(routingTable.Snapshot().Generation(routingKey)).To(Equal(generation))
+		})
+
+		It("changes the generation of ports whose rendered details changed", func() {
+			generation := routingTable.Snapshot().Generation(routingKey)
+			reweighted := createBackendServerInfo("some-ip-1", 1234, modificationTag)
+			reweighted.Weight = 10
+			routingTable.Rebuild(map[models.RoutingKey][]models.BackendServerInfo{
+				routingKey: {reweighted},
+			})
+			Expect(routingTable.Snapshot().Generation(routingKey)).NotTo(Equal(generation))
+		})
+	})
+
+	Describe("Generation", func() {
+		var routingKey models.RoutingKey
+
+		BeforeEach(func() {
+			routingKey = models.RoutingKey{Port: 12}
+			Expect(routingTable.UpsertBackendServerKey(routingKey, createBackendServerInfo("some-ip-1", 1234, modificationTag))).To(BeTrue())
+		})
+
+		It("changes when the entry for the port changes", func() {
+			generation := routingTable.Snapshot().Generation(routingKey)
+			Expect(generation).NotTo(BeZero())
+
+			Expect(routingTable.UpsertBackendServerKey(routingKey, createBackendServerInfo("some-ip-2", 1234, modificationTag))).To(BeTrue())
+			Expect(routingTable.Snapshot().Generation(routingKey)).To(BeNumerically(">", generation))
+		})
+
+		It("does not change when an event does not affect the routing configuration", func() {
+			generation := routingTable.Snapshot().Generation(routingKey)
+
+			newModificationTag := modificationTag
+			newModificationTag.Increment()
+			Expect(routingTable.UpsertBackendServerKey(routingKey, createBackendServerInfo("some-ip-1", 1234, newModificationTag))).To(BeFalse())
+			Expect(routingTable.Snapshot().Generation(routingKey)).To(Equal(generation))
+		})
+
+		It("is zero for ports that are not in the table", func() {
+			Expect(routingTable.Snapshot().Generation(models.RoutingKey{Port: 13})).To(BeZero())
+		})
+	})
+
 	Describe("BackendServerDetails", func() {
 		var (
 			now        = time.Now()
 			defaultTTL = 20
 		)
 
+		Describe("DifferentFrom", func() {
+			base := models.BackendServerDetails{
+				ModificationTag: routing_api_models.ModificationTag{Guid: "abc", Index: 1},
+				TTL:             120,
+				Weight:          10,
+				UpdatedTime:     now,
+			}
+			with := func(change func(*models.BackendServerDetails)) models.BackendServerDetails {
+				details := base
+				change(&details)
+				return details
+			}
+
+			DescribeTable("reports whether the rendered configuration differs",
+				func(other models.BackendServerDetails, different bool) {
+					Expect(base.DifferentFrom(other)).To(Equal(different))
+				},
+				Entry("identical details", base, false),
+				Entry("newer modification tag", with(func(d *models.BackendServerDetails) { d.ModificationTag.Index = 2 }), false),
+				Entry("different modification tag guid", with(func(d *models.BackendServerDetails) { d.ModificationTag.Guid = "def" }), false),
+				Entry("different TTL", with(func(d *models.BackendServerDetails) { d.TTL = 60 }), false),
+				Entry("different update time", with(func(d *models.BackendServerDetails) { d.UpdatedTime = now.Add(time.Minute) }), false),
+				Entry("different weight", with(func(d *models.BackendServerDetails) { d.Weight = 20 }), true),
+				Entry("weight removed", with(func(d *models.BackendServerDetails) { d.Weight = 0 }), true),
+				Entry("draining", with(func(d *models.BackendServerDetails) { d.DrainingSince = now }), true),
+			)
+		})
+
 		Context("when backend details have TTL", func() {
 			It("returns true if updated time is past expiration time", func() {
 				backendDetails := models.BackendServerDetails{TTL: 1, UpdatedTime: now.Add(-2 * time.Second)}
@@ -0,0 +1,72 @@
+package routing_table
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/cf-tcp-router/configurer/haproxy"
+	"code.cloudfoundry.org/cf-tcp-router/metrics_reporter/haproxy_client"
+	"code.cloudfoundry.org/cf-tcp-router/models"
+)
+
+type serverStatKey struct {
+	proxyName  string
+	serverName string
+}
+
+// drainReload is the reload that applied the drain of a backend, i.e. the
+// first reload after it began draining. Reloads are counted by the updater.
+type drainReload struct {
+	drainingSince time.Time
+	reload        uint64
+}
+
+type drainKey struct {
+	routingKey models.RoutingKey
+	backendKey models.BackendServerKey
+}
+
+// Returns the drain reloads of the draining backends of snapshot, keeping the
+// reloads of drains already applied and marking the others as applied by
+// reload.
+func markDrainReloads(reloads map[drainKey]drainReload, snapshot models.RoutingTableSnapshot, reload uint64) map[drainKey]drainReload {
+	marked := make(map[drainKey]drainReload, len(reloads))
+	for key, entry := range snapshot.Entries {
+		for backendKey, details := range entry.Backends {
+			if !details.Draining() {
+				continue
+			}
+			drain := drainKey{routingKey: key, backendKey: backendKey}
+			applied, found := reloads[drain]
+			if !found || !applied.drainingSince.Equal(details.DrainingSince) {
+				applied = drainReload{drainingSince: details.DrainingSince, reload: reload}
+			}
+			marked[drain] = applied
+		}
+	}
+	return marked
+}
+
+// Returns whether a backend has no current sessions. Backends missing from
+// stats, e.g. because HAProxy could not be reached, are not idle.
+//
+// Sessions open when HAProxy reloads stay with the old process, and the stats
+// only cover the new one. The stats therefore only tell whether a draining
+// backend is idle until the reload after the one that applied its drain;
+// backends that were reloaded again are left to the drain timeout.
+func idleBackends(stats haproxy_client.HaproxyStats, reloads map[drainKey]drainReload, lastReload uint64) func(models.RoutingKey, models.BackendServerKey, models.BackendServerDetails) bool {
+	sessions := make(map[serverStatKey]uint64, len(stats))
+	for _, stat := range stats {
+		sessions[serverStatKey{proxyName: stat.ProxyName, serverName: stat.ServerName}] = stat.CurrentSessions
+	}
+	return func(key models.RoutingKey, backend models.BackendServerKey, details models.BackendServerDetails) bool {
+		applied, found := reloads[drainKey{routingKey: key, backendKey: backend}]
+		if !found || !applied.drainingSince.Equal(details.DrainingSince) || applied.reload != lastReload {
+			return false
+		}
+		current, found := sessions[serverStatKey{
+			proxyName:  haproxy.ListenName(key.Port),
+			serverName: haproxy.ServerName(backend.Address, backend.Port),
+		}]
+		return found && current == 0
+	}
+}
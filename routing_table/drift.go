@@ -0,0 +1,78 @@
+package routing_table
+
+import (
+	"code.cloudfoundry.org/cf-tcp-router/metrics_reporter"
+	"code.cloudfoundry.org/cf-tcp-router/models"
+	"code.cloudfoundry.org/lager"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+)
+
+var (
+	driftBackendsAdded   = metrics_reporter.Value("RoutingTableDriftBackendsAdded")
+	driftBackendsRemoved = metrics_reporter.Value("RoutingTableDriftBackendsRemoved")
+	driftBackendsChanged = metrics_reporter.Value("RoutingTableDriftBackendsChanged")
+)
+
+// portDrift counts the backends of a port that the event stream got wrong, as
+// found by comparing the event-maintained routing table with a sync.
+type portDrift struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+}
+
+type routingTableDrift map[models.RoutingKey]portDrift
+
+// computeDrift summarizes the changes from the event-maintained routing table
+// previous to the one fetched during a sync. Backends without a modification
+// tag in previous were read back from the configuration file at startup, not
+// received as events, so the sync adopting their tag and TTL is not drift.
+func computeDrift(previous models.RoutingTableSnapshot, changeset models.RoutingTableChangeset) routingTableDrift {
+	drift := routingTableDrift{}
+	for _, key := range changeset.ChangedPorts() {
+		changed := 0
+		for _, info := range changeset.UpdatedBackends[key] {
+			backendKey := models.BackendServerKey{Address: info.Address, Port: info.Port}
+			if previous.Entries[key].Backends[backendKey].ModificationTag != (apimodels.ModificationTag{}) {
+				changed++
+			}
+		}
+		changes := portDrift{
+			Added:   len(changeset.AddedBackends[key]),
+			Removed: len(changeset.RemovedBackends[key]),
+			Changed: changed,
+		}
+		if changes != (portDrift{}) {
+			drift[key] = changes
+		}
+	}
+	return drift
+}
+
+func (d routingTableDrift) total() portDrift {
+	total := portDrift{}
+	for _, changes := range d {
+		total.Added += changes.Added
+		total.Removed += changes.Removed
+		total.Changed += changes.Changed
+	}
+	return total
+}
+
+func (d routingTableDrift) report(logger lager.Logger) {
+	total := d.total()
+	driftBackendsAdded.Send(uint64(total.Added))
+	driftBackendsRemoved.Send(uint64(total.Removed))
+	driftBackendsChanged.Send(uint64(total.Changed))
+
+	if len(d) == 0 {
+		logger.Debug("no-routing-table-drift")
+		return
+	}
+
+	ports := make(map[string]portDrift, len(d))
+	for key, changes := range d {
+		ports[key.String()] = changes
+	}
+	logger.Info("routing-table-drift-detected", lager.Data{"total": total, "ports": ports})
+}
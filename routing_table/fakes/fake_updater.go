@@ -4,6 +4,7 @@ package fakes
 import (
 	"sync"
 
+	"code.cloudfoundry.org/cf-tcp-router/metrics_reporter/haproxy_client"
 	"code.cloudfoundry.org/cf-tcp-router/routing_table"
 	"code.cloudfoundry.org/routing-api"
 )
@@ -26,9 +27,17 @@ type FakeUpdater struct {
 	syncingReturns     struct {
 		result1 bool
 	}
-	PruneStaleRoutesStub        func()
-	pruneStaleRoutesMutex       sync.RWMutex
-	pruneStaleRoutesArgsForCall []struct{}
+	PruneStaleRoutesStub             func()
+	pruneStaleRoutesMutex            sync.RWMutex
+	pruneStaleRoutesArgsForCall      []struct{}
+	RemoveDrainedBackendsStub        func(stats haproxy_client.HaproxyStats)
+	removeDrainedBackendsMutex       sync.RWMutex
+	removeDrainedBackendsArgsForCall []struct {
+		stats haproxy_client.HaproxyStats
+	}
+	ReleaseHeldPortsStub        func()
+	releaseHeldPortsMutex       sync.RWMutex
+	releaseHeldPortsArgsForCall []struct{}
 }
 
 func (fake *FakeUpdater) HandleEvent(event routing_api.TcpEvent) error {
@@ -117,4 +126,42 @@ func (fake *FakeUpdater) PruneStaleRoutesCallCount() int {
 	return len(fake.pruneStaleRoutesArgsForCall)
 }
 
+func (fake *FakeUpdater) RemoveDrainedBackends(stats haproxy_client.HaproxyStats) {
+	fake.removeDrainedBackendsMutex.Lock()
+	fake.removeDrainedBackendsArgsForCall = append(fake.removeDrainedBackendsArgsForCall, struct {
+		stats haproxy_client.HaproxyStats
+	}{stats})
+	fake.removeDrainedBackendsMutex.Unlock()
+	if fake.RemoveDrainedBackendsStub != nil {
+		fake.RemoveDrainedBackendsStub(stats)
+	}
+}
+
+func (fake *FakeUpdater) RemoveDrainedBackendsCallCount() int {
+	fake.removeDrainedBackendsMutex.RLock()
+	defer fake.removeDrainedBackendsMutex.RUnlock()
+	return len(fake.removeDrainedBackendsArgsForCall)
+}
+
+func (fake *FakeUpdater) RemoveDrainedBackendsArgsForCall(i int) haproxy_client.HaproxyStats {
+	fake.removeDrainedBackendsMutex.RLock()
+	defer fake.removeDrainedBackendsMutex.RUnlock()
+	return fake.removeDrainedBackendsArgsForCall[i].stats
+}
+
+func (fake *FakeUpdater) ReleaseHeldPorts() {
+	fake.releaseHeldPortsMutex.Lock()
+	fake.releaseHeldPortsArgsForCall = append(fake.releaseHeldPortsArgsForCall, struct{}{})
+	fake.releaseHeldPortsMutex.Unlock()
+	if fake.ReleaseHeldPortsStub != nil {
+		fake.ReleaseHeldPortsStub()
+	}
+}
+
+func (fake *FakeUpdater) ReleaseHeldPortsCallCount() int {
+	fake.releaseHeldPortsMutex.RLock()
+	defer fake.releaseHeldPortsMutex.RUnlock()
+	return len(fake.releaseHeldPortsArgsForCall)
+}
+
 var _ routing_table.Updater = new(FakeUpdater)
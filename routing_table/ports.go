@@ -0,0 +1,57 @@
+package routing_table
+
+import (
+	"code.cloudfoundry.org/cf-tcp-router/metrics_reporter"
+	"code.cloudfoundry.org/cf-tcp-router/models"
+)
+
+var (
+	portsOpened    = metrics_reporter.Value("TotalPortsOpened")
+	portsClosed    = metrics_reporter.Value("TotalPortsClosed")
+	portsRecovered = metrics_reporter.Value("TotalHeldPortsRecovered")
+	portsHeldDown  = metrics_reporter.Value("PortsHeldDown")
+)
+
+// portTracker counts the ports that the routing configuration binds and
+// releases, so that ports flapping open and closed show up in metrics. Held
+// ports that regain a backend are counted as recovered, as the hold down kept
+// them from closing.
+type portTracker struct {
+	held      map[models.RoutingKey]bool
+	opened    uint64
+	closed    uint64
+	recovered uint64
+}
+
+func newPortTracker() *portTracker {
+	return &portTracker{held: map[models.RoutingKey]bool{}}
+}
+
+// observe records the ports bound by the snapshot and sends the totals.
+func (t *portTracker) observe(snapshot models.RoutingTableSnapshot) {
+	heldDown := uint64(0)
+	for key, entry := range snapshot.Entries {
+		wasHeld, found := t.held[key]
+		held := entry.HeldDown()
+		if !found {
+			t.opened++
+		} else if wasHeld && !held {
+			t.recovered++
+		}
+		if held {
+			heldDown++
+		}
+		t.held[key] = held
+	}
+	for key := range t.held {
+		if _, found := snapshot.Entries[key]; !found {
+			t.closed++
+			delete(t.held, key)
+		}
+	}
+
+	portsOpened.Send(t.opened)
+	portsClosed.Send(t.closed)
+	portsRecovered.Send(t.recovered)
+	portsHeldDown.Send(heldDown)
+}
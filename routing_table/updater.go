@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"code.cloudfoundry.org/cf-tcp-router/configurer"
+	"code.cloudfoundry.org/cf-tcp-router/metrics_reporter/haproxy_client"
 	"code.cloudfoundry.org/cf-tcp-router/models"
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
@@ -19,6 +20,8 @@ type Updater interface {
 	Sync()
 	Syncing() bool
 	PruneStaleRoutes()
+	RemoveDrainedBackends(stats haproxy_client.HaproxyStats)
+	ReleaseHeldPorts()
 }
 
 type updater struct {
@@ -32,6 +35,9 @@ type updater struct {
 	lock             *sync.Mutex
 	klock            clock.Clock
 	defaultTTL       int
+	ports            *portTracker
+	reloads          uint64
+	drainReloads     map[drainKey]drainReload
 }
 
 func NewUpdater(logger lager.Logger, routingTable *models.RoutingTable, configurer configurer.RouterConfigurer,
@@ -47,6 +53,7 @@ func NewUpdater(logger lager.Logger, routingTable *models.RoutingTable, configur
 		cachedEvents:     nil,
 		klock:            klock,
 		defaultTTL:       defaultTTL,
+		ports:            newPortTracker(),
 	}
 }
 
@@ -63,6 +70,54 @@ func (u *updater) PruneStaleRoutes() {
 	u.routingTable.PruneEntries(u.defaultTTL)
 }
 
+// RemoveDrainedBackends removes draining backends whose sessions have ended
+// according to stats, or whose drain timeout has passed.
+func (u *updater) RemoveDrainedBackends(stats haproxy_client.HaproxyStats) {
+	logger := u.logger.Session("remove-drained-backends")
+	logger.Debug("starting")
+	defer logger.Debug("completed")
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if u.routingTable.RemoveDrainedBackends(idleBackends(stats, u.drainReloads, u.reloads)) && !u.syncing {
+		logger.Debug("calling-configurer")
+		err := u.configure()
+		if err != nil {
+			logger.Error("failed-to-configure", err)
+		}
+	}
+}
+
+// ReleaseHeldPorts unbinds the ports whose hold down has passed.
+func (u *updater) ReleaseHeldPorts() {
+	logger := u.logger.Session("release-held-ports")
+	logger.Debug("starting")
+	defer logger.Debug("completed")
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if u.routingTable.ReleaseHeldPorts() && !u.syncing {
+		logger.Debug("calling-configurer")
+		err := u.configure()
+		if err != nil {
+			logger.Error("failed-to-configure", err)
+		}
+	}
+}
+
+// configure applies the routing table to the configurer. Callers must hold the lock.
+func (u *updater) configure() error {
+	snapshot := u.routingTable.Snapshot()
+	u.ports.observe(snapshot)
+	err := u.configurer.Configure(snapshot)
+	// a failed configuration may still have reloaded HAProxy
+	u.reloads++
+	u.drainReloads = markDrainReloads(u.drainReloads, snapshot, u.reloads)
+	return err
+}
+
 func (u *updater) Sync() {
 	logger := u.logger.Session("bulk-sync")
 	logger.Debug("starting")
@@ -70,7 +125,7 @@ func (u *updater) Sync() {
 	defer func() {
 		u.lock.Lock()
 		u.applyCachedEvents(logger)
-		u.configurer.Configure(*u.routingTable)
+		u.configure()
 		logger.Debug("applied-fetched-routes-to-routing-table", lager.Data{"size": u.routingTable.Size()})
 		u.syncing = false
 		u.cachedEvents = nil
@@ -108,13 +163,15 @@ func (u *updater) Sync() {
 	}
 	logger.Debug("fetched-tcp-routes", lager.Data{"num-routes": len(tcpRouteMappings)})
 	if err == nil {
-		// Create a new map and populate using tcp route mappings we got from routing api
-		u.routingTable.Entries = make(map[models.RoutingKey]models.RoutingTableEntry)
+		// Rebuild the routing table using tcp route mappings we got from routing api
+		backends := make(map[models.RoutingKey][]models.BackendServerInfo)
 		for _, routeMapping := range tcpRouteMappings {
 			routingKey, backendServerInfo := u.toRoutingTableEntry(logger, routeMapping)
 			logger.Debug("creating-routing-table-entry", lager.Data{"key": routingKey, "value": backendServerInfo})
-			u.routingTable.UpsertBackendServerKey(routingKey, backendServerInfo)
+			backends[routingKey] = append(backends[routingKey], backendServerInfo)
 		}
+		previous := u.routingTable.Snapshot()
+		computeDrift(previous, u.routingTable.Rebuild(backends)).report(logger)
 	}
 }
 
@@ -184,7 +241,7 @@ func (u *updater) handleUpsert(logger lager.Logger, routeMapping apimodels.TcpRo
 
 	if u.routingTable.UpsertBackendServerKey(routingKey, backendServerInfo) && !u.syncing {
 		logger.Debug("calling-configurer")
-		return u.configurer.Configure(*u.routingTable)
+		return u.configure()
 	}
 
 	return nil
@@ -195,7 +252,7 @@ func (u *updater) handleDelete(logger lager.Logger, routeMapping apimodels.TcpRo
 
 	if u.routingTable.DeleteBackendServerKey(routingKey, backendServerInfo) && !u.syncing {
 		logger.Debug("calling-configurer")
-		return u.configurer.Configure(*u.routingTable)
+		return u.configure()
 	}
 
 	return nil
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"code.cloudfoundry.org/cf-tcp-router/configurer/fakes"
+	"code.cloudfoundry.org/cf-tcp-router/metrics_reporter/haproxy_client"
 	"code.cloudfoundry.org/cf-tcp-router/models"
 	"code.cloudfoundry.org/cf-tcp-router/routing_table"
 	"code.cloudfoundry.org/cf-tcp-router/testutil"
@@ -15,6 +16,8 @@ import (
 	routing_api_models "code.cloudfoundry.org/routing-api/models"
 	testUaaClient "code.cloudfoundry.org/uaa-go-client/fakes"
 	"code.cloudfoundry.org/uaa-go-client/schema"
+	"github.com/cloudfoundry/dropsonde/metric_sender/fake"
+	"github.com/cloudfoundry/dropsonde/metrics"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -290,6 +293,21 @@ var _ = Describe("Updater", func() {
 							Expect(err).To(HaveOccurred())
 						})
 					})
+
+					Context("when a stale upsert for the deleted backend arrives afterwards", func() {
+						It("does not re-add the backend or call configurer", func() {
+							err := updater.HandleEvent(tcpEvent)
+							Expect(err).NotTo(HaveOccurred())
+							Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(1))
+
+							staleEvent := tcpEvent
+							staleEvent.Action = "Upsert"
+							err = updater.HandleEvent(staleEvent)
+							Expect(err).NotTo(HaveOccurred())
+							Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(1))
+							Expect(routingTable.Get(existingRoutingKey5).Backends).To(HaveLen(1))
+						})
+					})
 				})
 
 				Context("and a new backend is provided", func() {
@@ -416,6 +434,73 @@ var _ = Describe("Updater", func() {
 				verifyRoutingTableEntry(models.RoutingKey{Port: externalPort2}, expectedRoutingTableEntry2)
 			})
 
+			Context("when the routing table has drifted from routing api", func() {
+				var sender *fake.FakeMetricSender
+
+				BeforeEach(func() {
+					sender = fake.NewFakeMetricSender()
+					metrics.Initialize(sender, nil)
+
+					driftedRoutingTableEntry := models.NewRoutingTableEntry(
+						[]models.BackendServerInfo{
+							models.BackendServerInfo{Address: "some-ip-1", Port: 61000, ModificationTag: modificationTag, TTL: ttl},
+							models.BackendServerInfo{Address: "some-ip-2", Port: 61001, ModificationTag: modificationTag, TTL: 10},
+							models.BackendServerInfo{Address: "some-ip-9", Port: 61009, ModificationTag: modificationTag, TTL: ttl},
+						},
+					)
+					Expect(routingTable.Set(models.RoutingKey{Port: externalPort1}, driftedRoutingTableEntry)).To(BeTrue())
+				})
+
+				It("logs and emits the drift", func() {
+					go invokeSync(doneChannel)
+					Eventually(doneChannel).Should(BeClosed())
+
+					Expect(logger).To(gbytes.Say("routing-table-drift-detected"))
+					Expect(sender.GetValue("RoutingTableDriftBackendsAdded")).To(Equal(fake.Metric{Value: float64(2), Unit: "Metric"}))
+					Expect(sender.GetValue("RoutingTableDriftBackendsRemoved")).To(Equal(fake.Metric{Value: float64(1), Unit: "Metric"}))
+					Expect(sender.GetValue("RoutingTableDriftBackendsChanged")).To(Equal(fake.Metric{Value: float64(1), Unit: "Metric"}))
+				})
+
+				Context("when the routing table was read back from the configuration file", func() {
+					BeforeEach(func() {
+						backends := map[models.RoutingKey][]models.BackendServerInfo{}
+						for _, mapping := range tcpMappings {
+							key := models.RoutingKey{Port: mapping.ExternalPort}
+							backends[key] = append(backends[key], models.BackendServerInfo{Address: mapping.HostIP, Port: mapping.HostPort})
+						}
+						for key, infos := range backends {
+							Expect(routingTable.Set(key, models.NewRoutingTableEntry(infos))).To(BeTrue())
+						}
+					})
+
+					It("does not report the backends adopting the tag and TTL of routing api", func() {
+						go invokeSync(doneChannel)
+						Eventually(doneChannel).Should(BeClosed())
+
+						Expect(logger).To(gbytes.Say("no-routing-table-drift"))
+						Expect(sender.GetValue("RoutingTableDriftBackendsChanged")).To(Equal(fake.Metric{Value: float64(0), Unit: "Metric"}))
+						Expect(routingTable.Get(models.RoutingKey{Port: externalPort1}).Backends[models.BackendServerKey{Address: "some-ip-1", Port: 61000}].ModificationTag).To(Equal(modificationTag))
+					})
+				})
+
+				Context("when the routing table matches routing api", func() {
+					BeforeEach(func() {
+						go invokeSync(doneChannel)
+						Eventually(doneChannel).Should(BeClosed())
+						doneChannel = make(chan struct{})
+					})
+
+					It("emits zero drift", func() {
+						go invokeSync(doneChannel)
+						Eventually(doneChannel).Should(BeClosed())
+
+						Expect(sender.GetValue("RoutingTableDriftBackendsAdded")).To(Equal(fake.Metric{Value: float64(0), Unit: "Metric"}))
+						Expect(sender.GetValue("RoutingTableDriftBackendsRemoved")).To(Equal(fake.Metric{Value: float64(0), Unit: "Metric"}))
+						Expect(sender.GetValue("RoutingTableDriftBackendsChanged")).To(Equal(fake.Metric{Value: float64(0), Unit: "Metric"}))
+					})
+				})
+			})
+
 			Context("when events are received", func() {
 				var (
 					syncChannel chan struct{}
@@ -647,4 +732,167 @@ var _ = Describe("Updater", func() {
 			})
 		})
 	})
+
+	Describe("RemoveDrainedBackends", func() {
+		var (
+			routingKey  models.RoutingKey
+			drainingKey models.BackendServerKey
+			stats       haproxy_client.HaproxyStats
+		)
+
+		BeforeEach(func() {
+			tmpRoutingTable := models.NewRoutingTable(logger).WithDrainTimeout(time.Hour)
+			routingTable = &tmpRoutingTable
+			routingKey = models.RoutingKey{Port: externalPort1}
+			drainingKey = models.BackendServerKey{Address: "some-ip-1", Port: 1234}
+			routingTable.Set(routingKey, models.NewRoutingTableEntry([]models.BackendServerInfo{
+				{Address: "some-ip-1", Port: 1234, ModificationTag: modificationTag},
+				{Address: "some-ip-2", Port: 1235, ModificationTag: modificationTag},
+			}))
+			stats = haproxy_client.HaproxyStats{
+				{ProxyName: "listen_cfg_2222", ServerName: "server_some-ip-1_1234", CurrentSessions: 3},
+				{ProxyName: "listen_cfg_2222", ServerName: "server_some-ip-2_1235", CurrentSessions: 0},
+			}
+			updater = routing_table.NewUpdater(logger, routingTable, fakeConfigurer, fakeRoutingApiClient, fakeUaaClient, fakeClock, defaultTTL)
+
+			tcpEvent = routing_api.TcpEvent{
+				TcpRouteMapping: apimodels.TcpRouteMapping{
+					TcpMappingEntity: apimodels.TcpMappingEntity{
+						RouterGroupGuid: routerGroupGuid,
+						HostPort:        1234,
+						HostIP:          "some-ip-1",
+						ExternalPort:    externalPort1,
+						ModificationTag: modificationTag,
+						TTL:             &ttl,
+					},
+				},
+				Action: "Delete",
+			}
+			Expect(updater.HandleEvent(tcpEvent)).To(Succeed())
+			Expect(routingTable.Get(routingKey).Backends[drainingKey].Draining()).To(BeTrue())
+			Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(1))
+		})
+
+		Context("when the draining backend still has sessions", func() {
+			It("keeps the backend", func() {
+				updater.RemoveDrainedBackends(stats)
+				Expect(routingTable.Get(routingKey).Backends).To(HaveKey(drainingKey))
+				Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the draining backend has no sessions", func() {
+			BeforeEach(func() {
+				stats[0].CurrentSessions = 0
+			})
+
+			It("removes the backend and calls the configurer", func() {
+				updater.RemoveDrainedBackends(stats)
+				Expect(routingTable.Get(routingKey).Backends).NotTo(HaveKey(drainingKey))
+				Expect(routingTable.Get(routingKey).Backends).To(HaveLen(1))
+				Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(2))
+			})
+		})
+
+		Context("when the draining backend is missing from the stats", func() {
+			It("keeps the backend", func() {
+				updater.RemoveDrainedBackends(nil)
+				Expect(routingTable.Get(routingKey).Backends).To(HaveKey(drainingKey))
+				Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when HAProxy reloaded again after the drain was applied", func() {
+			BeforeEach(func() {
+				stats[0].CurrentSessions = 0
+				tcpEvent = routing_api.TcpEvent{
+					TcpRouteMapping: apimodels.TcpRouteMapping{
+						TcpMappingEntity: apimodels.TcpMappingEntity{
+							RouterGroupGuid: routerGroupGuid,
+							HostPort:        61000,
+							HostIP:          "some-ip-3",
+							ExternalPort:    externalPort2,
+							ModificationTag: modificationTag,
+							TTL:             &ttl,
+						},
+					},
+					Action: "Upsert",
+				}
+				Expect(updater.HandleEvent(tcpEvent)).To(Succeed())
+				Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(2))
+			})
+
+			It("keeps the backend although the stats show no sessions", func() {
+				updater.RemoveDrainedBackends(stats)
+				Expect(routingTable.Get(routingKey).Backends).To(HaveKey(drainingKey))
+				Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(2))
+			})
+
+			It("removes the backend once the drain timeout passes", func() {
+				*routingTable = routingTable.WithDrainTimeout(time.Nanosecond)
+				updater.RemoveDrainedBackends(stats)
+				Expect(routingTable.Get(routingKey).Backends).NotTo(HaveKey(drainingKey))
+				Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(3))
+			})
+		})
+	})
+
+	Describe("ReleaseHeldPorts", func() {
+		var (
+			routingKey models.RoutingKey
+			sender     *fake.FakeMetricSender
+		)
+
+		BeforeEach(func() {
+			sender = fake.NewFakeMetricSender()
+			metrics.Initialize(sender, nil)
+
+			tmpRoutingTable := models.NewRoutingTable(logger).WithEmptyPortHoldDown(time.Nanosecond)
+			routingTable = &tmpRoutingTable
+			routingKey = models.RoutingKey{Port: externalPort1}
+			updater = routing_table.NewUpdater(logger, routingTable, fakeConfigurer, fakeRoutingApiClient, fakeUaaClient, fakeClock, defaultTTL)
+
+			tcpEvent = routing_api.TcpEvent{
+				TcpRouteMapping: apimodels.TcpRouteMapping{
+					TcpMappingEntity: apimodels.TcpMappingEntity{
+						RouterGroupGuid: routerGroupGuid,
+						HostPort:        61000,
+						HostIP:          "some-ip-1",
+						ExternalPort:    externalPort1,
+						ModificationTag: modificationTag,
+						TTL:             &ttl,
+					},
+				},
+				Action: "Upsert",
+			}
+			Expect(updater.HandleEvent(tcpEvent)).To(Succeed())
+			tcpEvent.Action = "Delete"
+			tcpEvent.TcpRouteMapping.ModificationTag.Increment()
+			Expect(updater.HandleEvent(tcpEvent)).To(Succeed())
+		})
+
+		It("keeps the port bound after its last backend is deleted", func() {
+			Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(2))
+			Expect(fakeConfigurer.ConfigureArgsForCall(1).Get(routingKey).HeldDown()).To(BeTrue())
+			Expect(sender.GetValue("PortsHeldDown")).To(Equal(fake.Metric{Value: float64(1), Unit: "Metric"}))
+			Expect(sender.GetValue("TotalPortsOpened")).To(Equal(fake.Metric{Value: float64(1), Unit: "Metric"}))
+			Expect(sender.GetValue("TotalPortsClosed")).To(Equal(fake.Metric{Value: float64(0), Unit: "Metric"}))
+		})
+
+		It("releases the port once the hold down passes", func() {
+			updater.ReleaseHeldPorts()
+			Expect(routingTable.Size()).To(Equal(0))
+			Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(3))
+			Expect(sender.GetValue("PortsHeldDown")).To(Equal(fake.Metric{Value: float64(0), Unit: "Metric"}))
+			Expect(sender.GetValue("TotalPortsClosed")).To(Equal(fake.Metric{Value: float64(1), Unit: "Metric"}))
+		})
+
+		It("counts held ports that regain a backend as recovered", func() {
+			tcpEvent.Action = "Upsert"
+			tcpEvent.TcpRouteMapping.ModificationTag.Increment()
+			Expect(updater.HandleEvent(tcpEvent)).To(Succeed())
+			Expect(sender.GetValue("TotalHeldPortsRecovered")).To(Equal(fake.Metric{Value: float64(1), Unit: "Metric"}))
+			Expect(sender.GetValue("PortsHeldDown")).To(Equal(fake.Metric{Value: float64(0), Unit: "Metric"}))
+		})
+	})
 })
@@ -35,6 +35,7 @@ func RandomFileName(prefix string, suffix string) string {
 func backendServerDetailsMatches(actualDetails, expectedDetails models.BackendServerDetails) {
 	Expect(actualDetails.ModificationTag).To(Equal(expectedDetails.ModificationTag))
 	Expect(actualDetails.TTL).To(Equal(expectedDetails.TTL))
+	Expect(actualDetails.Weight).To(Equal(expectedDetails.Weight))
 }
 
 func RoutingTableEntryMatches(actualEntry, expectedEntry models.RoutingTableEntry) {